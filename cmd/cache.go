@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klemjul/diffai/internal/config"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func cacheCommand() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of LLM responses.",
+		Args:  cobra.NoArgs,
+	}
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove every cached response.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := newResponseCache(viper.GetDuration(config.ENV_CACHE_TTL))
+			if err != nil {
+				return err
+			}
+			removed, err := cache.Clear()
+			if err != nil {
+				return fmt.Errorf("failed to clear cache: %v", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached responses\n", removed)
+			return nil
+		},
+	})
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Show the number and size of cached responses.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := newResponseCache(viper.GetDuration(config.ENV_CACHE_TTL))
+			if err != nil {
+				return err
+			}
+			stats, err := cache.Stats()
+			if err != nil {
+				return fmt.Errorf("failed to read cache: %v", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Directory: %s\nResponses: %d (%d expired)\nSize: %d bytes\n", cache.Dir(), stats.Entries, stats.Expired, stats.Size)
+			return nil
+		},
+	})
+
+	return cacheCmd
+}
+
+// newResponseCache opens the response cache of the user cache directory.
+func newResponseCache(ttl time.Duration) (*llm.ResponseCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cache directory: %v", err)
+	}
+	return llm.NewResponseCache(filepath.Join(cacheDir, "diffai", "responses"), ttl), nil
+}
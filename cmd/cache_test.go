@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newCachedReviewApp(content string) (*MockApp, *MockLLMClient) {
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+	mockLLMClient := &MockLLMClient{}
+	app.llm.
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(mockLLMClient, nil)
+	app.format.
+		On("FormatMarkdown", content).Return("["+content+"]", nil)
+	return app, mockLLMClient
+}
+
+func TestRun_WithCache_ShouldAnswerSameRequestFromCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	app, mockLLMClient := newCachedReviewApp("aires")
+	mockLLMClient.On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "aires", Usage: llm.LLMTokenUsage{InputTokens: 10, OutputTokens: 5}}, nil).
+		Once()
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache=false")
+	assert.NoError(t, err)
+	assert.Equal(t, "[aires]", output)
+	assert.Contains(t, stderr, "Usage: 10 input, 5 output tokens in ")
+
+	output, stderr, err = executeRootCommandOutputs(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache=false")
+	assert.NoError(t, err)
+	assert.Equal(t, "[aires]", output)
+	assert.Contains(t, stderr, "Usage: 0 input, 0 output tokens, 1 from cache in ")
+	mockLLMClient.AssertNumberOfCalls(t, "Send", 1)
+
+	output, err = executeRootCommand(app, "cache", "stats")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Responses: 1 (0 expired)")
+}
+
+func TestRun_WithCacheOtherPrompt_ShouldSendRequest(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	app, mockLLMClient := newCachedReviewApp("aires")
+	mockLLMClient.On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "aires"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache=false")
+	assert.NoError(t, err)
+	_, err = executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=other prompt", "--no-cache=false")
+	assert.NoError(t, err)
+
+	mockLLMClient.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestRun_WithNoCache_ShouldSendRequest(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	app, mockLLMClient := newCachedReviewApp("aires")
+	mockLLMClient.On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "aires"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache=false")
+	assert.NoError(t, err)
+	_, err = executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache")
+	assert.NoError(t, err)
+
+	mockLLMClient.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestCacheCommand_Clear(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	app, mockLLMClient := newCachedReviewApp("aires")
+	mockLLMClient.On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "aires"}, nil)
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--no-cache=false")
+	assert.NoError(t, err)
+
+	output, err := executeRootCommand(app, "cache", "clear")
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed 1 cached responses\n", output)
+
+	output, err = executeRootCommand(app, "cache", "stats")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Responses: 0 (0 expired)\nSize: 0 bytes\n")
+}
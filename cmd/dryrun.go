@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	DRY_RUN_FORMAT_TEXT = "text"
+	DRY_RUN_FORMAT_JSON = "json"
+	// DRY_RUN_NO_ENDPOINT stands for the endpoint of the offline providers,
+	// or of an ollama without OLLAMA_ENDPOINT.
+	DRY_RUN_NO_ENDPOINT = "none"
+)
+
+var dryRunFormats = []string{DRY_RUN_FORMAT_TEXT, DRY_RUN_FORMAT_JSON}
+
+// dryRunReport is what a review would send, printed by --dry-run instead of
+// sending it.
+type dryRunReport struct {
+	Provider  string   `json:"provider"`
+	Model     string   `json:"model"`
+	Fallbacks []string `json:"fallbacks"`
+	// Destinations tell where the requests of each target would go.
+	Destinations []dryRunDestination `json:"destinations"`
+	Generation   dryRunGeneration    `json:"generation"`
+	Command      string              `json:"command"`
+	Tokenizer    string              `json:"tokenizer"`
+	Tokens       int                 `json:"estimated_tokens"`
+	Chunked      bool                `json:"chunked"`
+	Series       bool                `json:"series"`
+	Requests     []dryRunRequest     `json:"requests"`
+}
+
+type dryRunGeneration struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int64    `json:"max_tokens,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type dryRunDestination struct {
+	Target   string            `json:"target"`
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+type dryRunRequest struct {
+	Tokens   int             `json:"estimated_tokens"`
+	Messages []dryRunMessage `json:"messages"`
+}
+
+type dryRunMessage struct {
+	Role    llm.MessageRole `json:"role"`
+	Content string          `json:"content"`
+}
+
+func newDryRunReport(targets []llm.FallbackTarget, opts llm.LLMClientOptions, command string, tokenizer llm.Tokenizer, requests [][]llm.Message, chunked bool) dryRunReport {
+	report := dryRunReport{
+		Provider:   string(targets[0].Provider),
+		Model:      targets[0].Model,
+		Fallbacks:  []string{},
+		Generation: dryRunGeneration(opts.Generation),
+		Command:    command,
+		Tokenizer:  tokenizer.Name(),
+		Chunked:    chunked,
+	}
+	for _, target := range targets[1:] {
+		report.Fallbacks = append(report.Fallbacks, target.String())
+	}
+	for i, target := range targets {
+		destination := llm.NewDestination(target.Provider, targetOptions(opts, target, i == 0))
+		report.Destinations = append(report.Destinations, dryRunDestination{
+			Target:   target.String(),
+			Endpoint: destination.Endpoint,
+			Headers:  destination.Headers,
+		})
+	}
+	for _, messages := range requests {
+		request := dryRunRequest{}
+		for _, message := range messages {
+			request.Tokens += tokenizer.CountTokens(message.Content)
+			request.Messages = append(request.Messages, dryRunMessage{Role: message.Role, Content: message.Content})
+		}
+		report.Tokens += request.Tokens
+		report.Requests = append(report.Requests, request)
+	}
+	return report
+}
+
+func (r dryRunReport) print(cmd *cobra.Command, format string) error {
+	out := cmd.OutOrStdout()
+	if format == DRY_RUN_FORMAT_JSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+
+	fmt.Fprintf(out, "Provider: %s\nModel: %s\n", r.Provider, r.Model)
+	if len(r.Fallbacks) > 0 {
+		fmt.Fprintf(out, "Fallbacks: %s\n", strings.Join(r.Fallbacks, ", "))
+	}
+	for _, destination := range r.Destinations {
+		fmt.Fprintf(out, "Destination: %s -> %s\n", destination.Target, cmp.Or(destination.Endpoint, DRY_RUN_NO_ENDPOINT))
+		for _, key := range slices.Sorted(maps.Keys(destination.Headers)) {
+			fmt.Fprintf(out, "  %s: %s\n", key, destination.Headers[key])
+		}
+	}
+	if generation, _ := json.Marshal(r.Generation); string(generation) != "{}" {
+		fmt.Fprintf(out, "Generation: %s\n", generation)
+	}
+	fmt.Fprintf(out, "Diff: %s\nEstimated tokens: %d (%s)\n", r.Command, r.Tokens, r.Tokenizer)
+	if r.Chunked {
+		fmt.Fprintf(out, "Chunked: %d part requests, then a request merging their reviews\n", len(r.Requests))
+	}
+	if r.Series {
+		fmt.Fprintf(out, "Series: %d commit requests, then a request summarizing the series\n", len(r.Requests))
+	}
+	for i, request := range r.Requests {
+		fmt.Fprintf(out, "\n=== Request %d/%d (%d tokens) ===\n", i+1, len(r.Requests), request.Tokens)
+		for _, message := range request.Messages {
+			fmt.Fprintf(out, "\n--- %s ---\n%s\n", message.Role, message.Content)
+		}
+	}
+	return nil
+}
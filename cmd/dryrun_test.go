@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newDryRunApp(diff string) *MockApp {
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(diff), FullCommand: "git diff --staged"}, nil)
+	return app
+}
+
+func TestRun_WithDryRun_ShouldPrintRequestWithoutSending(t *testing.T) {
+	t.Setenv("OLLAMA_ENDPOINT", "http://localhost:11434")
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_ORG_ID", "")
+	t.Setenv("OPENAI_PROJECT_ID", "")
+	app := newDryRunApp("diffout")
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--fallback", "openai:gpt-4.1", "--temperature", "0.2",
+		"--openai-organization", "org", "--openai-headers", "X-Tenant=team,Authorization=Bearer sk-secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Provider: ollama\n"+
+		"Model: model\n"+
+		"Fallbacks: openai:gpt-4.1\n"+
+		"Destination: ollama:model -> http://localhost:11434\n"+
+		"Destination: openai:gpt-4.1 -> https://api.openai.com/v1/\n"+
+		"  Authorization: [redacted]\n"+
+		"  OpenAI-Organization: org\n"+
+		"  X-Tenant: team\n"+
+		`Generation: {"temperature":0.2}`+"\n"+
+		"Diff: git diff --staged\n"+
+		"Estimated tokens: 4 (heuristic)\n"+
+		"\n=== Request 1/1 (4 tokens) ===\n"+
+		"\n--- system ---\nprompt\n"+
+		"\n--- user ---\ndiffout\n", output)
+	app.llm.AssertNotCalled(t, "NewClient", mock.Anything, mock.Anything)
+}
+
+func TestRun_WithDryRunJSON_ShouldPrintChunkRequests(t *testing.T) {
+	t.Setenv("OLLAMA_ENDPOINT", "http://localhost:11434")
+	app := newDryRunApp(chunkedDiff)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--diff-token-limit", "25", "--chunked", "--dry-run", "--dry-run-format", "json")
+
+	require.NoError(t, err)
+	var report dryRunReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, "ollama", report.Provider)
+	assert.Equal(t, []string{}, report.Fallbacks)
+	assert.Equal(t, []dryRunDestination{{Target: "ollama:model", Endpoint: "http://localhost:11434"}}, report.Destinations)
+	assert.True(t, report.Chunked)
+	require.Len(t, report.Requests, 2)
+	assert.Contains(t, report.Requests[0].Messages[1].Content, "part 1 of 2")
+	assert.Equal(t, report.Requests[0].Tokens+report.Requests[1].Tokens, report.Tokens)
+	app.llm.AssertNotCalled(t, "NewClient", mock.Anything, mock.Anything)
+}
+
+func TestRun_WithInvalidDryRunFormat_ShouldReturnError(t *testing.T) {
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--dry-run-format", "yaml")
+
+	assert.EqualError(t, err, "invalid DIFFAI_DRY_RUN_FORMAT 'yaml', must be one of [text json]")
+}
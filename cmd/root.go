@@ -1,18 +1,25 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/klemjul/diffai/internal/app"
 	"github.com/klemjul/diffai/internal/config"
+	"github.com/klemjul/diffai/internal/format"
 	"github.com/klemjul/diffai/internal/git"
 	"github.com/klemjul/diffai/internal/llm"
+	"github.com/klemjul/diffai/internal/review"
 	"github.com/klemjul/diffai/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -25,9 +32,12 @@ func RootCommand(app app.App) *cobra.Command {
 		Args:  cobra.RangeArgs(0, 2),
 		Example: `
 diffai main dev   # Review diff of two branches
+diffai main...dev   # Review changes of dev since it branched off main
 diffai abc123 def456   # Review diff of two commits
 diffai cdce10   # Review diff of a commit
+diffai main..feature --series   # Review each commit of feature, then the series
 diffai   # Review diff of staged changes
+diffai --worktree --untracked   # Review diff of unstaged changes and new files
 	`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return run(cmd, args, app)
@@ -47,19 +57,136 @@ diffai   # Review diff of staged changes
 		fmt.Sprintf("LLM provider to use. (env: %s)", config.GetEnvWithPrefix(config.ENV_PROVIDER)))
 	rootCmd.Flags().String("model", "",
 		fmt.Sprintf("LLM model to use, depends on the provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_MODEL)))
+	rootCmd.Flags().Float64("temperature", 0,
+		fmt.Sprintf("Sampling temperature, provider default when unset. (env: %s)", config.GetEnvWithPrefix(config.ENV_TEMPERATURE)))
+	rootCmd.Flags().Float64("top-p", 0,
+		fmt.Sprintf("Nucleus sampling probability, provider default when unset. (env: %s)", config.GetEnvWithPrefix(config.ENV_TOP_P)))
+	rootCmd.Flags().Int64("max-tokens", 0,
+		fmt.Sprintf("Maximum number of output tokens, provider default when unset. (env: %s)", config.GetEnvWithPrefix(config.ENV_MAX_TOKENS)))
+	rootCmd.Flags().Int64("seed", 0,
+		fmt.Sprintf("Sampling seed for reproducible reviews, when supported by the provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_SEED)))
+	rootCmd.Flags().StringSlice("stop", []string{},
+		fmt.Sprintf("Stop sequences ending the generation. (env: %s, comma separated)", config.GetEnvWithPrefix(config.ENV_STOP)))
 	rootCmd.Flags().BoolP("interactive", "i", false, "Run diffai in Chat Mode.")
-	rootCmd.Flags().Int("diff-token-limit", config.DEFAULT_DIFF_TOKEN_LIMIT,
-		fmt.Sprintf("Maximum number of tokens for the diff content. (env: %s)", config.GetEnvWithPrefix(config.ENV_DIFF_TOKEN_LIMIT)))
+	rootCmd.Flags().Bool("stream", false,
+		fmt.Sprintf("Print the review block by block while it is generated. (env: %s)", config.GetEnvWithPrefix(config.ENV_STREAM)))
+	rootCmd.Flags().Bool("dry-run", false,
+		fmt.Sprintf("Print the requests that would be sent to the model, without sending them. (env: %s)", config.GetEnvWithPrefix(config.ENV_DRY_RUN)))
+	rootCmd.Flags().String("dry-run-format", DRY_RUN_FORMAT_TEXT,
+		fmt.Sprintf("Format of --dry-run, one of %v. (env: %s)", dryRunFormats, config.GetEnvWithPrefix(config.ENV_DRY_RUN_FORMAT)))
+	rootCmd.Flags().Int("diff-token-limit", 0,
+		fmt.Sprintf("Maximum number of tokens for the diff content, defaults to a limit fitting the model context window or %d for unknown models. (env: %s)", config.DEFAULT_DIFF_TOKEN_LIMIT, config.GetEnvWithPrefix(config.ENV_DIFF_TOKEN_LIMIT)))
+	rootCmd.Flags().Bool("chunked", false,
+		fmt.Sprintf("Review a diff above the token limit file by file, then merge the partial reviews. (env: %s)", config.GetEnvWithPrefix(config.ENV_CHUNKED)))
+	rootCmd.Flags().Int("concurrency", config.DEFAULT_CONCURRENCY,
+		fmt.Sprintf("Number of parts reviewed at the same time with --chunked. (env: %s)", config.GetEnvWithPrefix(config.ENV_CONCURRENCY)))
+	rootCmd.Flags().StringSlice("fallback", []string{},
+		fmt.Sprintf("<provider>:<model> pairs answering in turn when the model fails. (env: %s, comma separated)", config.GetEnvWithPrefix(config.ENV_FALLBACK)))
+	rootCmd.Flags().Int("max-attempts", config.DEFAULT_MAX_ATTEMPTS,
+		fmt.Sprintf("Number of attempts of a request failing with a rate limit, timeout, server or connection error, 1 disables retries. (env: %s)", config.GetEnvWithPrefix(config.ENV_MAX_ATTEMPTS)))
+	rootCmd.Flags().Bool("no-cache", false,
+		fmt.Sprintf("Always send the requests instead of answering those already sent from the response cache. (env: %s)", config.GetEnvWithPrefix(config.ENV_NO_CACHE)))
+	rootCmd.Flags().Duration("cache-ttl", config.DEFAULT_CACHE_TTL,
+		fmt.Sprintf("Time a cached response is reused for. (env: %s)", config.GetEnvWithPrefix(config.ENV_CACHE_TTL)))
+	rootCmd.Flags().String("record", "",
+		fmt.Sprintf("Append the requests sent to the model and their answers to this transcript file. (env: %s)", config.GetEnvWithPrefix(config.ENV_RECORD)))
+	rootCmd.Flags().String("replay-file", "",
+		fmt.Sprintf("Transcript file recorded with --record answering the requests, used by the replay provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_REPLAY_FILE)))
+	rootCmd.Flags().String("mock-response", "",
+		fmt.Sprintf("Go template of the answers of the mock provider, given .Model, .Messages, .LastMessage, .Files, .Additions and .Deletions. (env: %s)", config.GetEnvWithPrefix(config.ENV_MOCK_RESPONSE)))
+	rootCmd.Flags().String("mock-response-file", "",
+		fmt.Sprintf("File holding the template of the answers of the mock provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_MOCK_RESPONSE_FILE)))
+	rootCmd.Flags().Duration("mock-delay", 0,
+		fmt.Sprintf("Delay between the words streamed by the mock provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_MOCK_DELAY)))
+	rootCmd.Flags().Int("mock-error-status", 0,
+		fmt.Sprintf("HTTP status of an error returned by the mock provider instead of answering. (env: %s)", config.GetEnvWithPrefix(config.ENV_MOCK_ERROR_STATUS)))
+	rootCmd.Flags().Int("mock-failures", 0,
+		fmt.Sprintf("Number of requests failing with --mock-error-status before the mock provider answers, 0 fails them all. (env: %s)", config.GetEnvWithPrefix(config.ENV_MOCK_FAILURES)))
+	rootCmd.Flags().String("models-file", "",
+		fmt.Sprintf("JSON file describing additional models, defaults to diffai/models.json in the user config directory. (env: %s)", config.GetEnvWithPrefix(config.ENV_MODELS_FILE)))
 	rootCmd.Flags().StringSliceP("diff-filters", "f", []string{}, "git diff -- <path> filters, used to limit the diff to the named paths or file exts")
+	rootCmd.Flags().Int("context-lines", config.DEFAULT_CONTEXT_LINES,
+		fmt.Sprintf("Number of unchanged lines shown around each change of the diff. (env: %s)", config.GetEnvWithPrefix(config.ENV_CONTEXT_LINES)))
+	rootCmd.Flags().Bool("function-context", false,
+		fmt.Sprintf("Show the whole function around each change of the diff. (env: %s)", config.GetEnvWithPrefix(config.ENV_FUNCTION_CONTEXT)))
+	rootCmd.Flags().Bool("full-files", false,
+		fmt.Sprintf("Add the full content of the modified files after the change while they fit in the diff token limit, the files with the most changed lines for their size first. (env: %s)", config.GetEnvWithPrefix(config.ENV_FULL_FILES)))
+	rootCmd.Flags().Bool("merge-base", false,
+		fmt.Sprintf("Compare two commits from their merge base, like <commit1>...<commit2>, to only review the changes of the second one. (env: %s)", config.GetEnvWithPrefix(config.ENV_MERGE_BASE)))
+	rootCmd.Flags().Bool("commit-log", false,
+		fmt.Sprintf("Add the message, author and trailers of the reviewed commits to the request, so the review checks the code against them. (env: %s)", config.GetEnvWithPrefix(config.ENV_COMMIT_LOG)))
+	rootCmd.Flags().Bool("series", false,
+		fmt.Sprintf("Review each commit of <commit1>..<commit2> on its own, then the whole series, flagging the commits to squash, split or reorder. (env: %s)", config.GetEnvWithPrefix(config.ENV_SERIES)))
+	rootCmd.Flags().Bool("worktree", false,
+		fmt.Sprintf("Review the changes of the working tree not staged yet, instead of the staged ones. (env: %s)", config.GetEnvWithPrefix(config.ENV_WORKTREE)))
+	rootCmd.Flags().Bool("all", false,
+		fmt.Sprintf("Review both the staged and unstaged changes against HEAD. (env: %s)", config.GetEnvWithPrefix(config.ENV_ALL)))
+	rootCmd.Flags().Bool("untracked", false,
+		fmt.Sprintf("Add the untracked files as new files to --worktree or --all. (env: %s)", config.GetEnvWithPrefix(config.ENV_UNTRACKED)))
+	rootCmd.MarkFlagsMutuallyExclusive("worktree", "all")
+	rootCmd.Flags().String("openai-base-url", "",
+		fmt.Sprintf("Base URL of an OpenAI-compatible API, used by the openai provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_OPENAI_BASE_URL)))
+	rootCmd.Flags().String("openai-organization", "",
+		fmt.Sprintf("OpenAI organization ID. (env: %s)", config.GetEnvWithPrefix(config.ENV_OPENAI_ORGANIZATION)))
+	rootCmd.Flags().String("openai-project", "",
+		fmt.Sprintf("OpenAI project ID. (env: %s)", config.GetEnvWithPrefix(config.ENV_OPENAI_PROJECT)))
+	rootCmd.Flags().StringSlice("openai-headers", []string{},
+		fmt.Sprintf("Extra <key>=<value> HTTP headers sent to the OpenAI-compatible API. (env: %s, comma separated)", config.GetEnvWithPrefix(config.ENV_OPENAI_HEADERS)))
+	rootCmd.Flags().String("azure-openai-endpoint", "",
+		fmt.Sprintf("Azure OpenAI resource endpoint, used by the azure-openai provider. (env: %s)", config.GetEnvWithPrefix(config.ENV_AZURE_ENDPOINT)))
+	rootCmd.Flags().String("azure-openai-deployment", "",
+		fmt.Sprintf("Azure OpenAI deployment name, defaults to the model. (env: %s)", config.GetEnvWithPrefix(config.ENV_AZURE_DEPLOYMENT)))
+	rootCmd.Flags().String("azure-openai-api-version", "",
+		fmt.Sprintf("Azure OpenAI API version, defaults to %s. (env: %s)", llm.AZURE_OPENAI_DEFAULT_API_VERSION, config.GetEnvWithPrefix(config.ENV_AZURE_API_VERSION)))
 
+	viper.BindPFlag(config.ENV_DRY_RUN, rootCmd.Flags().Lookup("dry-run"))
+	viper.BindPFlag(config.ENV_DRY_RUN_FORMAT, rootCmd.Flags().Lookup("dry-run-format"))
 	viper.BindPFlag(config.ENV_DIFF_TOKEN_LIMIT, rootCmd.Flags().Lookup("diff-token-limit"))
+	viper.BindPFlag(config.ENV_CHUNKED, rootCmd.Flags().Lookup("chunked"))
+	viper.BindPFlag(config.ENV_CONCURRENCY, rootCmd.Flags().Lookup("concurrency"))
+	viper.BindPFlag(config.ENV_FALLBACK, rootCmd.Flags().Lookup("fallback"))
+	viper.BindPFlag(config.ENV_MAX_ATTEMPTS, rootCmd.Flags().Lookup("max-attempts"))
+	viper.BindPFlag(config.ENV_NO_CACHE, rootCmd.Flags().Lookup("no-cache"))
+	viper.BindPFlag(config.ENV_CACHE_TTL, rootCmd.Flags().Lookup("cache-ttl"))
+	viper.BindPFlag(config.ENV_RECORD, rootCmd.Flags().Lookup("record"))
+	viper.BindPFlag(config.ENV_REPLAY_FILE, rootCmd.Flags().Lookup("replay-file"))
+	viper.BindPFlag(config.ENV_MOCK_RESPONSE, rootCmd.Flags().Lookup("mock-response"))
+	viper.BindPFlag(config.ENV_MOCK_RESPONSE_FILE, rootCmd.Flags().Lookup("mock-response-file"))
+	viper.BindPFlag(config.ENV_MOCK_DELAY, rootCmd.Flags().Lookup("mock-delay"))
+	viper.BindPFlag(config.ENV_MOCK_ERROR_STATUS, rootCmd.Flags().Lookup("mock-error-status"))
+	viper.BindPFlag(config.ENV_MOCK_FAILURES, rootCmd.Flags().Lookup("mock-failures"))
+	viper.BindPFlag(config.ENV_MODELS_FILE, rootCmd.Flags().Lookup("models-file"))
+	viper.BindPFlag(config.ENV_CONTEXT_LINES, rootCmd.Flags().Lookup("context-lines"))
+	viper.BindPFlag(config.ENV_FUNCTION_CONTEXT, rootCmd.Flags().Lookup("function-context"))
+	viper.BindPFlag(config.ENV_FULL_FILES, rootCmd.Flags().Lookup("full-files"))
+	viper.BindPFlag(config.ENV_MERGE_BASE, rootCmd.Flags().Lookup("merge-base"))
+	viper.BindPFlag(config.ENV_COMMIT_LOG, rootCmd.Flags().Lookup("commit-log"))
+	viper.BindPFlag(config.ENV_SERIES, rootCmd.Flags().Lookup("series"))
+	viper.BindPFlag(config.ENV_WORKTREE, rootCmd.Flags().Lookup("worktree"))
+	viper.BindPFlag(config.ENV_ALL, rootCmd.Flags().Lookup("all"))
+	viper.BindPFlag(config.ENV_UNTRACKED, rootCmd.Flags().Lookup("untracked"))
 	viper.BindPFlag(config.ENV_PROMPT, rootCmd.Flags().Lookup("prompt"))
 	viper.BindPFlag(config.ENV_PROVIDER, rootCmd.Flags().Lookup("provider"))
 	viper.BindPFlag(config.ENV_MODEL, rootCmd.Flags().Lookup("model"))
+	viper.BindPFlag(config.ENV_TEMPERATURE, rootCmd.Flags().Lookup("temperature"))
+	viper.BindPFlag(config.ENV_TOP_P, rootCmd.Flags().Lookup("top-p"))
+	viper.BindPFlag(config.ENV_MAX_TOKENS, rootCmd.Flags().Lookup("max-tokens"))
+	viper.BindPFlag(config.ENV_SEED, rootCmd.Flags().Lookup("seed"))
+	viper.BindPFlag(config.ENV_STOP, rootCmd.Flags().Lookup("stop"))
+	viper.BindPFlag(config.ENV_STREAM, rootCmd.Flags().Lookup("stream"))
+	viper.BindPFlag(config.ENV_OPENAI_BASE_URL, rootCmd.Flags().Lookup("openai-base-url"))
+	viper.BindPFlag(config.ENV_OPENAI_ORGANIZATION, rootCmd.Flags().Lookup("openai-organization"))
+	viper.BindPFlag(config.ENV_OPENAI_PROJECT, rootCmd.Flags().Lookup("openai-project"))
+	viper.BindPFlag(config.ENV_OPENAI_HEADERS, rootCmd.Flags().Lookup("openai-headers"))
+	viper.BindPFlag(config.ENV_AZURE_ENDPOINT, rootCmd.Flags().Lookup("azure-openai-endpoint"))
+	viper.BindPFlag(config.ENV_AZURE_DEPLOYMENT, rootCmd.Flags().Lookup("azure-openai-deployment"))
+	viper.BindPFlag(config.ENV_AZURE_API_VERSION, rootCmd.Flags().Lookup("azure-openai-api-version"))
 
 	viper.SetEnvPrefix(config.ENV_PREFIX)
 	viper.AutomaticEnv()
 
+	rootCmd.AddCommand(cacheCommand())
+
 	return rootCmd
 }
 
@@ -69,8 +196,9 @@ func validate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid provider '%s'. Valid providers are: %v", provider, llm.LLMProviders)
 	}
 
+	// a transcript or the mock answer whatever the model
 	model := viper.GetString("MODEL")
-	if model == "" {
+	if model == "" && !isOffline(llm.LLMProvider(provider)) {
 		return fmt.Errorf("model must be specified '%s'", model)
 	}
 	prompt := viper.GetString("PROMPT")
@@ -83,10 +211,56 @@ func validate(cmd *cobra.Command, args []string) error {
 
 func run(cmd *cobra.Command, args []string, app app.App) error {
 
-	diffTokenLimit := viper.GetInt(config.ENV_DIFF_TOKEN_LIMIT)
+	concurrency := viper.GetInt(config.ENV_CONCURRENCY)
+	if concurrency < 1 {
+		return fmt.Errorf("invalid %s '%s', must be greater than 0", config.GetEnvWithPrefix(config.ENV_CONCURRENCY), viper.GetString(config.ENV_CONCURRENCY))
+	}
+	maxAttempts := viper.GetInt(config.ENV_MAX_ATTEMPTS)
+	if maxAttempts < 1 {
+		return fmt.Errorf("invalid %s '%s', must be greater than 0", config.GetEnvWithPrefix(config.ENV_MAX_ATTEMPTS), viper.GetString(config.ENV_MAX_ATTEMPTS))
+	}
+	cacheTTL := viper.GetDuration(config.ENV_CACHE_TTL)
+	if cacheTTL <= 0 {
+		return fmt.Errorf("invalid %s '%s', must be greater than 0", config.GetEnvWithPrefix(config.ENV_CACHE_TTL), viper.GetString(config.ENV_CACHE_TTL))
+	}
+	contextLines := viper.GetInt(config.ENV_CONTEXT_LINES)
+	if contextLines < 0 {
+		return fmt.Errorf("invalid %s '%s', must not be negative", config.GetEnvWithPrefix(config.ENV_CONTEXT_LINES), viper.GetString(config.ENV_CONTEXT_LINES))
+	}
+	dryRunFormat := viper.GetString(config.ENV_DRY_RUN_FORMAT)
+	if !slices.Contains(dryRunFormats, dryRunFormat) {
+		return fmt.Errorf("invalid %s '%s', must be one of %v", config.GetEnvWithPrefix(config.ENV_DRY_RUN_FORMAT), dryRunFormat, dryRunFormats)
+	}
 	model := viper.GetString(config.ENV_MODEL)
 	provider := viper.GetString(config.ENV_PROVIDER)
 	prompt := viper.GetString(config.ENV_PROMPT)
+	models, err := loadModelRegistry()
+	if err != nil {
+		return err
+	}
+	var modelInfo *llm.ModelInfo
+	if info, ok := models.Lookup(llm.LLMProvider(provider), model); ok {
+		modelInfo = &info
+	}
+	diffTokenLimit := viper.GetInt(config.ENV_DIFF_TOKEN_LIMIT)
+	if diffTokenLimit <= 0 {
+		diffTokenLimit = config.DEFAULT_DIFF_TOKEN_LIMIT
+		if modelInfo != nil {
+			diffTokenLimit = modelInfo.DefaultDiffTokenLimit()
+		}
+	}
+	generation, err := getGenerationOptions()
+	if err != nil {
+		return err
+	}
+	openaiHeaders, err := parseKeyValues(getStringList(config.ENV_OPENAI_HEADERS))
+	if err != nil {
+		return fmt.Errorf("invalid openai headers: %v", err)
+	}
+	fallbacks, err := parseFallbacks(getStringList(config.ENV_FALLBACK))
+	if err != nil {
+		return err
+	}
 	promptNo, err := strconv.Atoi(prompt)
 	if err == nil {
 		promptEnv := fmt.Sprintf("%s_%v", config.ENV_PROMPT, promptNo)
@@ -109,6 +283,26 @@ func run(cmd *cobra.Command, args []string, app app.App) error {
 		diffFilters = diffFilters[1:]
 	}
 
+	worktree := viper.GetBool(config.ENV_WORKTREE)
+	all := viper.GetBool(config.ENV_ALL)
+	untracked := viper.GetBool(config.ENV_UNTRACKED)
+	// cobra only sees the flags, not the environment
+	if worktree && all {
+		return fmt.Errorf("--worktree and --all can't be used together")
+	}
+	if (worktree || all) && len(args) > 0 {
+		return fmt.Errorf("--worktree and --all review the working tree, they don't take commits")
+	}
+	if untracked && !worktree && !all {
+		return fmt.Errorf("--untracked requires --worktree or --all")
+	}
+
+	refs, mergeBase := parseRefs(args)
+	series := viper.GetBool(config.ENV_SERIES)
+	if series && len(refs) != 2 {
+		return fmt.Errorf("--series reviews a range of commits, like main..feature")
+	}
+
 	var diffRes git.DiffResult
 	workingDirectory, err := os.Getwd()
 	if err != nil {
@@ -116,19 +310,26 @@ func run(cmd *cobra.Command, args []string, app app.App) error {
 	}
 
 	options := git.DiffOptions{
-		CliPath:     "git",
-		CliWd:       workingDirectory,
-		Unified:     3,
-		FindRenames: true,
-		Filters:     diffFilters,
+		CliPath:         "git",
+		CliWd:           workingDirectory,
+		Unified:         contextLines,
+		FindRenames:     true,
+		FunctionContext: viper.GetBool(config.ENV_FUNCTION_CONTEXT),
+		Filters:         diffFilters,
+		Untracked:       untracked,
+		MergeBase:       mergeBase || viper.GetBool(config.ENV_MERGE_BASE),
 	}
 
-	switch len(args) {
-	case 2:
-		to, from := args[0], args[1]
+	switch {
+	case worktree:
+		diffRes, err = app.Git().DiffWorktree(options)
+	case all:
+		diffRes, err = app.Git().DiffAll(options)
+	case len(refs) == 2:
+		to, from := refs[0], refs[1]
 		diffRes, err = app.Git().DiffRefs(to, from, options)
-	case 1:
-		ref := args[0]
+	case len(refs) == 1:
+		ref := refs[0]
 		diffRes, err = app.Git().DiffCommit(ref, options)
 	default:
 		diffRes, err = app.Git().DiffStaged(options)
@@ -138,24 +339,35 @@ func run(cmd *cobra.Command, args []string, app app.App) error {
 		return fmt.Errorf("error generating diff: %v", err)
 	}
 
+	// the working tree modes have no commits to describe
+	var commits []git.Commit
+	if (viper.GetBool(config.ENV_COMMIT_LOG) || series) && !worktree && !all {
+		switch len(refs) {
+		case 2:
+			commits, err = app.Git().LogRefs(refs[0], refs[1], options)
+		case 1:
+			commits, err = app.Git().LogCommit(refs[0], options)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading commit log: %v", err)
+		}
+	}
+
 	diffContent := string(diffRes.Out)
 
-	if llm.RoughEstimateCodeTokens(diffContent) > diffTokenLimit {
-		return fmt.Errorf("diff exceeds estimated token limit of %d tokens. Please reduce the diff size or extend token limit", diffTokenLimit)
+	tokenizer := app.LLM().NewTokenizer(model)
+	// a series is limited by the diff of each commit instead
+	chunked := !series && tokenizer.CountTokens(diffContent) > diffTokenLimit
+	if chunked && !viper.GetBool(config.ENV_CHUNKED) {
+		return fmt.Errorf("diff exceeds estimated token limit of %d tokens. Please reduce the diff size, extend token limit or use --chunked", diffTokenLimit)
 	}
 
 	if strings.TrimSpace(diffContent) == "" {
 		return fmt.Errorf("no diff content found. Please ensure you have staged changes or valid git references")
 	}
-	client, err := app.LLM().NewClient(llm.LLMProvider(provider), llm.LLMClientOptions{
-		Model: model,
-	})
+	targets := append([]llm.FallbackTarget{{Provider: llm.LLMProvider(provider), Model: model}}, fallbacks...)
 
-	if err != nil {
-		return fmt.Errorf("failed to create LLM client: %v", err)
-	}
-
-	initialMessages := []llm.Message{
+	initialMessages := review.WithCommits([]llm.Message{
 		{
 			Role:    llm.System,
 			Content: prompt,
@@ -166,48 +378,561 @@ func run(cmd *cobra.Command, args []string, app app.App) error {
 			Content: diffContent,
 			Hidden:  true,
 		},
-	}
+	}, commits)
 
-	if !interactive {
-		aiRes, err := client.Send(cmd.Context(), initialMessages)
+	// the files only fill the room left by a diff, and its commits, fitting
+	// in a single request
+	if viper.GetBool(config.ENV_FULL_FILES) && !chunked && !series {
+		used := tokenizer.CountTokens(initialMessages[len(initialMessages)-1].Content)
+		files, err := fullFiles(app, diffRes, postChangeRef(refs, worktree || all), options, tokenizer, diffTokenLimit-used)
 		if err != nil {
-			return fmt.Errorf("failed to generate response: %v", err)
+			return err
 		}
-		formattedRes, err := app.Format().FormatMarkdown(aiRes.Content)
+		initialMessages = review.WithFiles(initialMessages, files)
+	}
+
+	var seriesCommits []git.Commit
+	var seriesReqs [][]llm.Message
+	if series {
+		if seriesCommits, seriesReqs, err = seriesRequests(app, tokenizer, prompt, commits, options, diffTokenLimit); err != nil {
+			return err
+		}
+	}
+
+	clientOptions := llm.LLMClientOptions{
+		Model:      model,
+		Generation: generation,
+		OpenAI: llm.OpenAIOptions{
+			BaseURL:      viper.GetString(config.ENV_OPENAI_BASE_URL),
+			Organization: viper.GetString(config.ENV_OPENAI_ORGANIZATION),
+			Project:      viper.GetString(config.ENV_OPENAI_PROJECT),
+			Headers:      openaiHeaders,
+		},
+		Azure: llm.AzureOpenAIOptions{
+			Endpoint:   viper.GetString(config.ENV_AZURE_ENDPOINT),
+			Deployment: viper.GetString(config.ENV_AZURE_DEPLOYMENT),
+			APIVersion: viper.GetString(config.ENV_AZURE_API_VERSION),
+		},
+		Replay: llm.ReplayOptions{
+			File: viper.GetString(config.ENV_REPLAY_FILE),
+		},
+		Mock: llm.MockOptions{
+			Response:     viper.GetString(config.ENV_MOCK_RESPONSE),
+			ResponseFile: viper.GetString(config.ENV_MOCK_RESPONSE_FILE),
+			ChunkDelay:   viper.GetDuration(config.ENV_MOCK_DELAY),
+			ErrorStatus:  viper.GetInt(config.ENV_MOCK_ERROR_STATUS),
+			Failures:     viper.GetInt(config.ENV_MOCK_FAILURES),
+		},
+	}
+	if viper.GetBool(config.ENV_DRY_RUN) {
+		requests := [][]llm.Message{initialMessages}
+		if chunked {
+			if _, requests, err = chunkRequests(tokenizer, prompt, diffRes, commits, diffTokenLimit); err != nil {
+				return err
+			}
+		}
+		if series {
+			requests = seriesReqs
+		}
+		report := newDryRunReport(targets, clientOptions, diffRes.FullCommand, tokenizer, requests, chunked)
+		report.Series = series
+		return report.print(cmd, dryRunFormat)
+	}
+
+	client, err := newLLMClient(cmd, app, targets, clientOptions, models, maxAttempts, interactive)
+	if err != nil {
+		return err
+	}
+	recordFile := viper.GetString(config.ENV_RECORD)
+	if recordFile != "" {
+		client, err = llm.NewRecordingClient(client, recordFile)
 		if err != nil {
-			return fmt.Errorf("failed to format response: %v", err)
+			return fmt.Errorf("failed to open record file: %v", err)
 		}
-		cmd.OutOrStdout().Write([]byte(formattedRes))
+	}
+	// transcripts hold the answers of the model, not of the cache, and
+	// without a cache directory reviews are just not cached
+	if !viper.GetBool(config.ENV_NO_CACHE) && recordFile == "" && !isOffline(llm.LLMProvider(provider)) {
+		if cache, err := newResponseCache(cacheTTL); err == nil {
+			client = llm.NewCachingClient(client, cache, func(messages []llm.Message) string {
+				return llm.CacheKey(llm.LLMProvider(provider), clientOptions, messages)
+			}, targets[0].String())
+		}
+	}
+	usage := &reviewUsage{
+		pricing: func(answeredBy string) *llm.ModelInfo {
+			if answeredBy == "" {
+				return modelInfo
+			}
+			provider, model, _ := strings.Cut(answeredBy, ":")
+			if info, ok := models.Lookup(llm.LLMProvider(provider), model); ok {
+				return &info
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	switch {
+	case chunked:
+		initialMessages, err = reviewInChunks(cmd, client, tokenizer, prompt, diffRes, commits, diffTokenLimit, concurrency, usage)
+	case series:
+		initialMessages, err = reviewSeries(cmd, client, prompt, seriesCommits, seriesReqs, concurrency, usage)
+	}
+	if err != nil {
+		return err
+	}
 
-	} else {
+	switch {
+	case interactive:
 		TUIModel := app.TUI().InitialModel(ui.InitialModelOptions{
 			Title:          diffRes.FullCommand,
 			Messages:       initialMessages,
-			GetBotResponse: makeLLMBotResponder(client, cmd.Context()),
+			Context:        cmd.Context(),
+			GetBotResponse: makeLLMBotResponder(client),
+			Usage:          usage.report,
+			Pricing:        usage.pricing,
 		})
 		if _, err := app.TUI().Run(TUIModel); err != nil {
 			return fmt.Errorf("error running interactive mode: %v", err)
 		}
+	case viper.GetBool(config.ENV_STREAM):
+		aiRes, err := streamResponse(cmd, app, client, initialMessages)
+		if err != nil {
+			return err
+		}
+		usage.add(aiRes)
+		usage.print(cmd, aiRes.AnsweredBy, time.Since(start))
+	default:
+		aiRes, err := client.Send(cmd.Context(), initialMessages)
+		if err != nil {
+			return fmt.Errorf("failed to generate response: %v", err)
+		}
+		formattedRes, err := app.Format().FormatMarkdown(aiRes.Content)
+		if err != nil {
+			return fmt.Errorf("failed to format response: %v", err)
+		}
+		cmd.OutOrStdout().Write([]byte(formattedRes))
+		usage.add(aiRes)
+		usage.print(cmd, aiRes.AnsweredBy, time.Since(start))
 	}
 	return nil
 }
 
-func makeLLMBotResponder(client llm.LLMClient, ctx context.Context) func([]llm.Message) tea.Cmd {
-	return func(messages []llm.Message) tea.Cmd {
+// newLLMClient creates the client of every target, adapted to the features
+// of its model and retrying its transient errors, and chains them when there
+// are fallbacks.
+func newLLMClient(cmd *cobra.Command, app app.App, targets []llm.FallbackTarget, opts llm.LLMClientOptions, models *llm.ModelRegistry, maxAttempts int, interactive bool) (llm.LLMClient, error) {
+	retry := llm.RetryOptions{MaxAttempts: maxAttempts}
+	fallback := llm.FallbackOptions{}
+	// stderr would garble the Chat Mode screen
+	if !interactive {
+		retry.OnRetry = func(attempt int, err error, delay time.Duration) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Attempt %d/%d failed (%s), retrying in %.1fs: %v\n", attempt, maxAttempts, llm.ClassifyError(err), delay.Seconds(), err)
+		}
+		fallback.OnFallback = func(target llm.FallbackTarget, err error, next llm.FallbackTarget) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s failed, falling back to %s: %v\n", target, next, err)
+		}
+	}
+
+	for i := range targets {
+		client, err := app.LLM().NewClient(targets[i].Provider, targetOptions(opts, targets[i], i == 0))
+		if err != nil && i == 0 {
+			return nil, fmt.Errorf("failed to create LLM client: %v", err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM client for fallback %s: %v", targets[i], err)
+		}
+		if info, ok := models.Lookup(targets[i].Provider, targets[i].Model); ok {
+			client = llm.NewFeaturesClient(client, info, app.LLM().NewTokenizer(targets[i].Model))
+		}
+		targets[i].Client = llm.NewRetryingClient(client, retry)
+	}
+
+	if len(targets) == 1 {
+		return targets[0].Client, nil
+	}
+	return llm.NewFallbackClient(targets, fallback), nil
+}
+
+// targetOptions returns the client options of target. The Azure deployment
+// is the one of the primary model, a fallback target defaults it to its own
+// model.
+func targetOptions(opts llm.LLMClientOptions, target llm.FallbackTarget, primary bool) llm.LLMClientOptions {
+	opts.Model = target.Model
+	if !primary {
+		opts.Azure.Deployment = ""
+	}
+	return opts
+}
+
+// parseRefs splits a <commit1>...<commit2> range, reported as compared from
+// the merge base, or a <commit1>..<commit2> one into two commits. A missing
+// side defaults to HEAD like in git.
+func parseRefs(args []string) ([]string, bool) {
+	if len(args) != 1 {
+		return args, false
+	}
+	for _, separator := range []string{"...", ".."} {
+		if from, to, found := strings.Cut(args[0], separator); found {
+			return []string{cmp.Or(from, "HEAD"), cmp.Or(to, "HEAD")}, separator == "..."
+		}
+	}
+	return args, false
+}
+
+// isOffline reports whether provider answers without any model, its answers
+// are then neither cached nor tied to a model name.
+func isOffline(provider llm.LLMProvider) bool {
+	return provider == llm.LLMProviderReplay || provider == llm.LLMProviderMock
+}
+
+// parseFallbacks reads <provider>:<model> pairs, the model may hold a colon
+// like ollama tags.
+func parseFallbacks(entries []string) ([]llm.FallbackTarget, error) {
+	var targets []llm.FallbackTarget
+	for _, entry := range entries {
+		provider, model, found := strings.Cut(entry, ":")
+		if !found || model == "" {
+			return nil, fmt.Errorf("invalid fallback '%s', expected <provider>:<model>", entry)
+		}
+		if !slices.Contains(llm.LLMProviders, llm.LLMProvider(provider)) {
+			return nil, fmt.Errorf("invalid fallback provider '%s'. Valid providers are: %v", provider, llm.LLMProviders)
+		}
+		targets = append(targets, llm.FallbackTarget{Provider: llm.LLMProvider(provider), Model: model})
+	}
+	return targets, nil
+}
+
+// reviewUsage sums the usage of a review, each answer priced with the model
+// that gave it.
+type reviewUsage struct {
+	report  llm.UsageReport
+	pricing func(answeredBy string) *llm.ModelInfo
+}
+
+func (u *reviewUsage) add(res *llm.LLMSendResponse) {
+	u.report.Add(res.Usage, u.pricing(res.AnsweredBy))
+	if res.Cached {
+		u.report.Cached++
+	}
+}
+
+// print reports the tokens, cost and time spent on stderr, to keep them out
+// of a redirected review.
+func (u *reviewUsage) print(cmd *cobra.Command, answeredBy string, elapsed time.Duration) {
+	if answeredBy != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "\nAnswered by %s", answeredBy)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "\nUsage: %s in %.1fs\n", u.report, elapsed.Seconds())
+}
+
+// reviewInChunks reviews a diff too large for a single request part by part,
+// and returns the messages asking to merge the partial reviews, which then
+// go through the usual output modes. Parts are reviewed concurrently, a
+// failed part is reported and flagged to the synthesis unless every part
+// failed. The usage of the parts is added to usage.
+func reviewInChunks(cmd *cobra.Command, client llm.LLMClient, tokenizer llm.Tokenizer, prompt string, diffRes git.DiffResult, commits []git.Commit, diffTokenLimit int, concurrency int, usage *reviewUsage) ([]llm.Message, error) {
+	chunks, requests, err := chunkRequests(tokenizer, prompt, diffRes, commits, diffTokenLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Reviewing %d parts, %d at a time\n", len(chunks), concurrency)
+	reviews, err := sendReviews(cmd, client, requests, concurrency, usage,
+		func(i int) string { return fmt.Sprintf("part %d/%d", i+1, len(chunks)) },
+		func(i int) string { return fmt.Sprintf(" (%d files)", len(chunks[i].Paths)) })
+	if err != nil {
+		return nil, err
+	}
+	return review.SynthesisMessages(prompt, chunks, reviews), nil
+}
+
+// sendReviews sends the requests of a review made of several parts, name
+// naming request i in the progress messages and details describing it once
+// reviewed. A failed request is reported and its review left empty, unless
+// every request failed. The usage of the requests is added to usage.
+func sendReviews(cmd *cobra.Command, client llm.LLMClient, requests [][]llm.Message, concurrency int, usage *reviewUsage, name func(i int) string, details func(i int) string) ([]string, error) {
+	results := llm.SendBatch(cmd.Context(), client, requests, llm.LLMBatchOptions{
+		Concurrency: concurrency,
+		OnDone: func(i int, result llm.LLMBatchResult) {
+			if result.Err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Failed to review %s: %v\n", name(i), result.Err)
+				return
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Reviewed %s%s\n", name(i), details(i))
+		},
+	})
+	if err := cmd.Context().Err(); err != nil {
+		return nil, fmt.Errorf("review cancelled: %v", err)
+	}
+
+	reviews := make([]string, len(requests))
+	reviewed := 0
+	for i, result := range results {
+		if result.Err == nil {
+			reviews[i] = result.Response.Content
+			usage.add(result.Response)
+			reviewed++
+		}
+	}
+	if reviewed == 0 {
+		return nil, fmt.Errorf("failed to review %s: %v", name(0), results[0].Err)
+	}
+	return reviews, nil
+}
+
+// chunkRequests splits a diff too large for a single request into the
+// requests reviewing each part, each one describing the commits.
+func chunkRequests(tokenizer llm.Tokenizer, prompt string, diffRes git.DiffResult, commits []git.Commit, diffTokenLimit int) ([]review.Chunk, [][]llm.Message, error) {
+	files, err := diffRes.Files()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse diff: %v", err)
+	}
+
+	chunks := review.SplitDiff(files, diffTokenLimit, tokenizer.CountTokens)
+	requests := make([][]llm.Message, len(chunks))
+	for i, chunk := range chunks {
+		requests[i] = review.WithCommits(review.ChunkMessages(prompt, chunk, i, len(chunks)), commits)
+	}
+	return chunks, requests, nil
+}
+
+// postChangeRef returns the ref holding the files after the reviewed change.
+func postChangeRef(refs []string, workingTree bool) string {
+	switch {
+	case workingTree:
+		return git.WORKTREE
+	case len(refs) > 0:
+		return refs[len(refs)-1]
+	default:
+		return git.INDEX
+	}
+}
+
+// fullFiles reads the content at ref of the files modified by diffRes, new
+// and deleted files being whole in the diff already, and selects the ones
+// fitting in tokenLimit.
+func fullFiles(app app.App, diffRes git.DiffResult, ref string, options git.DiffOptions, tokenizer llm.Tokenizer, tokenLimit int) ([]review.File, error) {
+	diffFiles, err := diffRes.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %v", err)
+	}
+
+	var files []review.File
+	for _, file := range diffFiles {
+		if file.Binary || file.Combined || !file.Regular() || len(file.Hunks) == 0 || file.Status == git.FileStatusAdded || file.Status == git.FileStatusDeleted {
+			continue
+		}
+		content, err := app.Git().ReadFile(ref, file.Path(), options)
+		if errors.Is(err, git.ErrNotRegularFile) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", file.Path(), err)
+		}
+		files = append(files, review.File{Path: file.Path(), Content: string(content), Changed: file.Additions() + file.Deletions()})
+	}
+	return review.SelectFiles(files, tokenLimit, tokenizer.CountTokens), nil
+}
+
+// loadModelRegistry adds the models of the models file to the built-in ones.
+// The default file is optional, a file given explicitly must exist.
+func loadModelRegistry() (*llm.ModelRegistry, error) {
+	models := llm.NewModelRegistry()
+	path := viper.GetString(config.ENV_MODELS_FILE)
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return models, nil
+		}
+		path = filepath.Join(configDir, "diffai", "models.json")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return models, nil
+		}
+	}
+
+	if err := models.LoadFile(path); err != nil {
+		return nil, fmt.Errorf("failed to load models file: %v", err)
+	}
+	return models, nil
+}
+
+// streamResponse renders each markdown block as soon as the model completes
+// it, so long generations show progress instead of a silent wait.
+func streamResponse(cmd *cobra.Command, app app.App, client llm.LLMClient, messages []llm.Message) (*llm.LLMSendResponse, error) {
+	res := &llm.LLMSendResponse{}
+	blocks := format.MarkdownBlockBuffer{}
+	writeBlock := func(block string) error {
+		formattedBlock, err := app.Format().FormatMarkdown(block)
+		if err != nil {
+			return fmt.Errorf("failed to format response: %v", err)
+		}
+		_, err = cmd.OutOrStdout().Write([]byte(formattedBlock))
+		return err
+	}
+
+	for event := range client.Stream(cmd.Context(), messages) {
+		switch event.Type {
+		case llm.LLMStreamEventTypeMessage:
+			for _, block := range blocks.Write(event.Content) {
+				if err := writeBlock(block); err != nil {
+					return nil, err
+				}
+			}
+		case llm.LLMStreamEventTypeComplete:
+			res = &llm.LLMSendResponse{Content: event.Content, Usage: event.Usage, AnsweredBy: event.AnsweredBy, Cached: event.Cached}
+		case llm.LLMStreamEventTypeError:
+			return nil, fmt.Errorf("failed to generate response: %s", event.Content)
+		}
+	}
+
+	if block := blocks.Flush(); block != "" {
+		return res, writeBlock(block)
+	}
+	return res, nil
+}
+
+func makeLLMBotResponder(client llm.LLMClient) func(context.Context, []llm.Message) tea.Cmd {
+	return func(ctx context.Context, messages []llm.Message) tea.Cmd {
 		return func() tea.Msg {
-			aiRes, err := client.Send(ctx, messages)
+			stream := client.Stream(ctx, messages)
+			return readLLMStreamEvent(ctx, stream, &strings.Builder{})()
+		}
+	}
+}
 
-			if err != nil {
+// readLLMStreamEvent turns the next stream event into a chat chunk, or into
+// the final assistant message once the stream completes or fails. A failure
+// caused by cancelling ctx keeps the partial answer, marked as interrupted.
+func readLLMStreamEvent(ctx context.Context, stream <-chan llm.LLMStreamEvent, content *strings.Builder) tea.Cmd {
+	return func() tea.Msg {
+		for event := range stream {
+			switch event.Type {
+			case llm.LLMStreamEventTypeMessage:
+				content.WriteString(event.Content)
+				return ui.ChatStreamChunk{
+					Content: event.Content,
+					Next:    readLLMStreamEvent(ctx, stream, content),
+				}
+			case llm.LLMStreamEventTypeError:
+				if ctx.Err() != nil {
+					return llm.Message{
+						Role:        llm.Assistant,
+						Content:     content.String(),
+						Interrupted: true,
+					}
+				}
+				failure := fmt.Sprintf("Failed to generate response: %v", event.Content)
+				if content.Len() > 0 {
+					failure = content.String() + "\n\n" + failure
+				}
 				return llm.Message{
 					Role:    llm.Assistant,
-					Content: fmt.Sprintf("Failed to generate response: %v", err.Error()),
+					Content: failure,
+				}
+			case llm.LLMStreamEventTypeComplete:
+				return llm.Message{
+					Role:       llm.Assistant,
+					Content:    content.String(),
+					Usage:      event.Usage,
+					AnsweredBy: event.AnsweredBy,
 				}
 			}
+		}
+		return llm.Message{
+			Role:        llm.Assistant,
+			Content:     content.String(),
+			Interrupted: ctx.Err() != nil,
+		}
+	}
+}
 
-			return llm.Message{
-				Role:    llm.Assistant,
-				Content: aiRes.Content,
-			}
+// getGenerationOptions only sets the sampling parameters explicitly given
+// through flags or env, the others keep the provider defaults.
+func getGenerationOptions() (llm.GenerationOptions, error) {
+	var generation llm.GenerationOptions
+	var err error
+
+	if generation.Temperature, err = getOptionalFloat(config.ENV_TEMPERATURE); err != nil {
+		return generation, err
+	}
+	if generation.TopP, err = getOptionalFloat(config.ENV_TOP_P); err != nil {
+		return generation, err
+	}
+	if generation.Seed, err = getOptionalInt(config.ENV_SEED); err != nil {
+		return generation, err
+	}
+	maxTokens, err := getOptionalInt(config.ENV_MAX_TOKENS)
+	if err != nil {
+		return generation, err
+	}
+	if maxTokens != nil {
+		if *maxTokens <= 0 {
+			return generation, fmt.Errorf("invalid %s '%d', must be greater than 0", config.GetEnvWithPrefix(config.ENV_MAX_TOKENS), *maxTokens)
+		}
+		generation.MaxTokens = *maxTokens
+	}
+	generation.Stop = getStringList(config.ENV_STOP)
+
+	return generation, nil
+}
+
+func getOptionalFloat(key string) (*float64, error) {
+	if !viper.IsSet(key) {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(viper.GetString(key)), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s', must be a number", config.GetEnvWithPrefix(key), viper.GetString(key))
+	}
+	return &value, nil
+}
+
+func getOptionalInt(key string) (*int64, error) {
+	if !viper.IsSet(key) {
+		return nil, nil
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(viper.GetString(key)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s', must be an integer", config.GetEnvWithPrefix(key), viper.GetString(key))
+	}
+	return &value, nil
+}
+
+// getStringList reads a list setting that is either a repeated flag or a
+// comma separated environment variable.
+func getStringList(key string) []string {
+	var entries []string
+	switch value := viper.Get(key).(type) {
+	case string:
+		entries = strings.Split(value, ",")
+	case []string:
+		entries = value
+	}
+
+	var list []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && entry != "[]" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+func parseKeyValues(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, found := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("'%s' is not a <key>=<value> pair", entry)
 		}
+		values[key] = strings.TrimSpace(value)
 	}
+	return values, nil
 }
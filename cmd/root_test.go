@@ -3,11 +3,12 @@ package cmd
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/klemjul/diffai/internal/app"
@@ -30,6 +31,16 @@ func (m *MockGitService) DiffStaged(diffOptions git.DiffOptions) (git.DiffResult
 	return args.Get(0).(git.DiffResult), args.Error(1)
 }
 
+func (m *MockGitService) DiffWorktree(diffOptions git.DiffOptions) (git.DiffResult, error) {
+	args := m.Called(diffOptions)
+	return args.Get(0).(git.DiffResult), args.Error(1)
+}
+
+func (m *MockGitService) DiffAll(diffOptions git.DiffOptions) (git.DiffResult, error) {
+	args := m.Called(diffOptions)
+	return args.Get(0).(git.DiffResult), args.Error(1)
+}
+
 func (m *MockGitService) DiffRefs(refFrom string, refTo string, diffOptions git.DiffOptions) (git.DiffResult, error) {
 	args := m.Called(refFrom, refTo, diffOptions)
 	return args.Get(0).(git.DiffResult), args.Error(1)
@@ -40,6 +51,21 @@ func (m *MockGitService) DiffCommit(ref string, diffOptions git.DiffOptions) (gi
 	return args.Get(0).(git.DiffResult), args.Error(1)
 }
 
+func (m *MockGitService) LogRefs(refFrom string, refTo string, diffOptions git.DiffOptions) ([]git.Commit, error) {
+	args := m.Called(refFrom, refTo, diffOptions)
+	return args.Get(0).([]git.Commit), args.Error(1)
+}
+
+func (m *MockGitService) LogCommit(ref string, diffOptions git.DiffOptions) ([]git.Commit, error) {
+	args := m.Called(ref, diffOptions)
+	return args.Get(0).([]git.Commit), args.Error(1)
+}
+
+func (m *MockGitService) ReadFile(ref string, path string, diffOptions git.DiffOptions) ([]byte, error) {
+	args := m.Called(ref, path, diffOptions)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 type MockTUIService struct {
 	mock.Mock
 }
@@ -56,6 +82,7 @@ func (m *MockTUIService) Run(model ui.ChatTUIModel) (returnModel tea.Model, retu
 
 type MockLLMService struct {
 	mock.Mock
+	tokenizer llm.Tokenizer
 }
 
 func (m *MockLLMService) NewClient(provider llm.LLMProvider, opts llm.LLMClientOptions) (llm.LLMClient, error) {
@@ -63,6 +90,19 @@ func (m *MockLLMService) NewClient(provider llm.LLMProvider, opts llm.LLMClientO
 	return args.Get(0).(llm.LLMClient), args.Error(1)
 }
 
+// NewTokenizer uses the heuristic unless a test sets its own tokenizer.
+func (m *MockLLMService) NewTokenizer(model string) llm.Tokenizer {
+	if m.tokenizer != nil {
+		return m.tokenizer
+	}
+	return llm.HeuristicTokenizer{}
+}
+
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+func (wordTokenizer) Name() string                { return "words" }
+
 type MockLLMClient struct {
 	mock.Mock
 }
@@ -104,6 +144,16 @@ func NewMockApp() app.App {
 
 func TestMain(m *testing.M) {
 	clearEnvWithPrefix(config.ENV_PREFIX)
+	// Keep a models file of the user config directory out of the tests.
+	configDir, _ := os.MkdirTemp("", "diffai-config")
+	defer os.RemoveAll(configDir)
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+	// Tests sending the same request would get the answers cached by the
+	// previous ones, the cache tests enable it in their own directory.
+	cacheDir, _ := os.MkdirTemp("", "diffai-cache")
+	defer os.RemoveAll(cacheDir)
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	os.Setenv(config.GetEnvWithPrefix(config.ENV_NO_CACHE), "true")
 	m.Run()
 }
 
@@ -118,11 +168,19 @@ func clearEnvWithPrefix(prefix string) {
 }
 
 func executeRootCommand(app app.App, args ...string) (string, error) {
+	stdout, stderr, err := executeRootCommandOutputs(app, args...)
+	return stdout + stderr, err
+}
+
+// executeRootCommandOutputs keeps the review on stdout apart from the
+// progress and usage reports on stderr.
+func executeRootCommandOutputs(app app.App, args ...string) (string, string, error) {
 	viper.Reset()
 	cmd := RootCommand(app)
-	buf := new(bytes.Buffer)
-	cmd.SetOut(buf)
-	cmd.SetErr(buf)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
 	cmd.SetArgs(args)
 
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
@@ -130,7 +188,7 @@ func executeRootCommand(app app.App, args ...string) (string, error) {
 	})
 
 	_, err := cmd.ExecuteC()
-	return buf.String(), err
+	return stdout.String(), stderr.String(), err
 }
 
 func TestRun_WithNoArgs_ShouldCallDiffStaged(t *testing.T) {
@@ -173,9 +231,10 @@ func TestRun_WithNoArgs_ShouldCallDiffStaged(t *testing.T) {
 			Content: "aires",
 		}, nil)
 
-	output, _ := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt")
+	output, stderr, _ := executeRootCommandOutputs(app, "--provider", "ollama", "--model=model", "-p=prompt")
 
 	assert.Equal(t, "formated res", output)
+	assert.Contains(t, stderr, "Usage: 0 input, 0 output tokens in ")
 	app.Git().(*MockGitService).AssertExpectations(t)
 	app.LLM().(*MockLLMService).AssertExpectations(t)
 	app.Format().(*MockFormatClient).AssertExpectations(t)
@@ -221,9 +280,10 @@ func TestRun_WithOneArgs_ShouldCallDiffCommit(t *testing.T) {
 			Content: "modelores",
 		}, nil)
 
-	output, _ := executeRootCommand(app, "shacommit", "--provider", "openai", "--model=modelo", "-p=prompt2")
+	output, stderr, _ := executeRootCommandOutputs(app, "shacommit", "--provider", "openai", "--model=modelo", "-p=prompt2")
 
 	assert.Equal(t, "formated modelores", output)
+	assert.Contains(t, stderr, "Usage: 0 input, 0 output tokens in ")
 	app.Git().(*MockGitService).AssertExpectations(t)
 	app.LLM().(*MockLLMService).AssertExpectations(t)
 	app.Format().(*MockFormatClient).AssertExpectations(t)
@@ -269,14 +329,284 @@ func TestRun_WithTwoArgs_ShouldCallDiffRefs(t *testing.T) {
 			Content: "ollamamres",
 		}, nil)
 
-	output, _ := executeRootCommand(app, "diffFrom", "diffTo", "--provider", "ollama", "--model=ollamam", "-p=prompt3")
+	output, stderr, _ := executeRootCommandOutputs(app, "diffFrom", "diffTo", "--provider", "ollama", "--model=ollamam", "-p=prompt3")
 
 	assert.Equal(t, "formated ollamamres", output)
+	assert.Contains(t, stderr, "Usage: 0 input, 0 output tokens in ")
 	app.Git().(*MockGitService).AssertExpectations(t)
 	app.LLM().(*MockLLMService).AssertExpectations(t)
 	app.Format().(*MockFormatClient).AssertExpectations(t)
 }
 
+func TestRun_WithRange_ShouldCallDiffRefs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		from      string
+		to        string
+		mergeBase bool
+	}{
+		{"three dots", []string{"main...dev"}, "main", "dev", true},
+		{"three dots from HEAD", []string{"main..."}, "main", "HEAD", true},
+		{"two dots", []string{"main..dev"}, "main", "dev", false},
+		{"merge base flag", []string{"main", "dev", "--merge-base"}, "main", "dev", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewMockApp()
+			app.Git().(*MockGitService).
+				On("DiffRefs", tt.from, tt.to, mock.MatchedBy(func(opts git.DiffOptions) bool { return opts.MergeBase == tt.mergeBase })).
+				Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+			_, err := executeRootCommand(app, append([]string{"--provider", "ollama", "--model=model", "-p=prompt", "--dry-run"}, tt.args...)...)
+
+			assert.NoError(t, err)
+			app.Git().(*MockGitService).AssertExpectations(t)
+		})
+	}
+}
+
+func TestRun_WithCommitLog_ShouldDescribeCommits(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffRefs", "main", "dev", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+	app.Git().(*MockGitService).
+		On("LogRefs", "main", "dev", mock.AnythingOfType("git.DiffOptions")).
+		Return([]git.Commit{{Hash: "abc", Author: "Jane <jane@example.com>", Date: "2025-01-02", Subject: "Fix parser", Trailers: []git.Trailer{{Key: "Fixes", Value: "#12"}}}}, nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--commit-log", "main..dev")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "## Commits\n\n### abc Fix parser\n\nAuthor: Jane <jane@example.com>\nDate: 2025-01-02\nFixes: #12\n\n## Diff\n\ndiffout\n")
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithCommitLogError_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffCommit", "abc", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+	app.Git().(*MockGitService).
+		On("LogCommit", "abc", mock.AnythingOfType("git.DiffOptions")).
+		Return([]git.Commit(nil), fmt.Errorf("bad revision"))
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--commit-log", "abc")
+
+	assert.EqualError(t, err, "error reading commit log: bad revision")
+}
+
+func TestRun_WithCommitLogOnWorktree_ShouldIgnoreIt(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffWorktree", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--commit-log", "--worktree")
+
+	assert.NoError(t, err)
+	assert.NotContains(t, output, "## Commits")
+	app.Git().(*MockGitService).AssertNotCalled(t, "LogCommit", mock.Anything, mock.Anything)
+}
+
+func TestRun_WithContextOptions_ShouldSetDiffOptions(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.MatchedBy(func(opts git.DiffOptions) bool { return opts.Unified == 10 && opts.FunctionContext })).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--context-lines", "10", "--function-context")
+
+	assert.NoError(t, err)
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithNegativeContextLines_ShouldReturnError(t *testing.T) {
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--context-lines", "-1")
+
+	assert.EqualError(t, err, "invalid DIFFAI_CONTEXT_LINES '-1', must not be negative")
+}
+
+func TestRun_WithFullFiles_ShouldAddFilesFittingTheLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		ref  string
+	}{
+		{"staged", []string{}, git.INDEX},
+		{"worktree", []string{"--worktree"}, git.WORKTREE},
+		{"commit", []string{"abc"}, "abc"},
+		{"range", []string{"main..dev"}, "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewMockApp()
+			for _, method := range []string{"DiffStaged", "DiffWorktree"} {
+				app.Git().(*MockGitService).
+					On(method, mock.AnythingOfType("git.DiffOptions")).
+					Return(git.DiffResult{Out: []byte(chunkedDiff), FullCommand: "fullcommand"}, nil).Maybe()
+			}
+			app.Git().(*MockGitService).
+				On("DiffCommit", "abc", mock.AnythingOfType("git.DiffOptions")).
+				Return(git.DiffResult{Out: []byte(chunkedDiff), FullCommand: "fullcommand"}, nil).Maybe()
+			app.Git().(*MockGitService).
+				On("DiffRefs", "main", "dev", mock.AnythingOfType("git.DiffOptions")).
+				Return(git.DiffResult{Out: []byte(chunkedDiff), FullCommand: "fullcommand"}, nil).Maybe()
+			app.Git().(*MockGitService).
+				On("ReadFile", tt.ref, "a.go", mock.AnythingOfType("git.DiffOptions")).
+				Return([]byte("new a\n"), nil)
+			app.Git().(*MockGitService).
+				On("ReadFile", tt.ref, "b.go", mock.AnythingOfType("git.DiffOptions")).
+				Return([]byte(strings.Repeat("new b\n", 100)), nil)
+
+			output, err := executeRootCommand(app, append([]string{"--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--full-files", "--diff-token-limit", "100"}, tt.args...)...)
+
+			assert.NoError(t, err)
+			assert.Contains(t, output, "## Files")
+			assert.Contains(t, output, "### a.go\n\n```\nnew a\n```")
+			assert.NotContains(t, output, "### b.go")
+			app.Git().(*MockGitService).AssertExpectations(t)
+		})
+	}
+}
+
+func TestRun_WithFullFiles_ShouldSkipEntriesNotRegularFiles(t *testing.T) {
+	diff := "diff --git a/lib b/lib\n" +
+		"index 8352675..a903574 160000\n" +
+		"--- a/lib\n" +
+		"+++ b/lib\n" +
+		"@@ -1 +1 @@\n" +
+		"-Subproject commit 8352675\n" +
+		"+Subproject commit a903574\n" +
+		chunkedDiff
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffWorktree", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(diff), FullCommand: "fullcommand"}, nil)
+	app.Git().(*MockGitService).
+		On("ReadFile", git.WORKTREE, "a.go", mock.AnythingOfType("git.DiffOptions")).
+		Return([]byte(nil), fmt.Errorf("a.go: %w", git.ErrNotRegularFile))
+	app.Git().(*MockGitService).
+		On("ReadFile", git.WORKTREE, "b.go", mock.AnythingOfType("git.DiffOptions")).
+		Return([]byte("new b\n"), nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--full-files", "--worktree")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "### b.go\n\n```\nnew b\n```")
+	assert.NotContains(t, output, "### a.go")
+	app.Git().(*MockGitService).AssertNotCalled(t, "ReadFile", git.WORKTREE, "lib", mock.Anything)
+}
+
+func TestRun_WithFullFilesAndCommitLog_ShouldLeaveRoomForTheCommits(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffCommit", "abc", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(chunkedDiff), FullCommand: "fullcommand"}, nil)
+	app.Git().(*MockGitService).
+		On("LogCommit", "abc", mock.AnythingOfType("git.DiffOptions")).
+		Return([]git.Commit{{Hash: "abc", Subject: "subject", Body: strings.Repeat("body ", 60)}}, nil)
+	app.Git().(*MockGitService).
+		On("ReadFile", "abc", mock.Anything, mock.AnythingOfType("git.DiffOptions")).
+		Return([]byte("new a\n"), nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run", "--full-files", "--commit-log", "--diff-token-limit", "100", "abc")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "## Commits")
+	assert.NotContains(t, output, "## Files")
+}
+
+func TestRun_WithWorktree_ShouldCallDiffWorktree(t *testing.T) {
+	app := NewMockApp()
+	wd, _ := os.Getwd()
+	app.Git().(*MockGitService).
+		On("DiffWorktree", git.DiffOptions{
+			CliPath:     "git",
+			CliWd:       wd,
+			Unified:     3,
+			FindRenames: true,
+			Filters:     []string{},
+			Untracked:   true,
+		}).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--worktree", "--untracked", "--dry-run")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "--- user ---\ndiffout")
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithAll_ShouldCallDiffAll(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffAll", mock.MatchedBy(func(opts git.DiffOptions) bool { return !opts.Untracked })).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--all", "--dry-run")
+
+	assert.NoError(t, err)
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithWorktreeFromEnv_ShouldCallDiffWorktree(t *testing.T) {
+	t.Setenv("DIFFAI_WORKTREE", "true")
+	t.Setenv("DIFFAI_UNTRACKED", "true")
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffWorktree", mock.MatchedBy(func(opts git.DiffOptions) bool { return opts.Untracked })).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run")
+
+	assert.NoError(t, err)
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithAllFromEnv_ShouldCallDiffAll(t *testing.T) {
+	t.Setenv("DIFFAI_ALL", "true")
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffAll", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--dry-run")
+
+	assert.NoError(t, err)
+	app.Git().(*MockGitService).AssertExpectations(t)
+}
+
+func TestRun_WithWorktreeFlagAndAllFromEnv_ShouldReturnError(t *testing.T) {
+	t.Setenv("DIFFAI_ALL", "true")
+
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--worktree")
+
+	assert.ErrorContains(t, err, "--worktree and --all can't be used together")
+}
+
+func TestRun_WithInvalidWorktreeFlags_ShouldReturnError(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"worktree with commit", []string{"--worktree", "abc123"}, "--worktree and --all review the working tree, they don't take commits"},
+		{"untracked alone", []string{"--untracked"}, "--untracked requires --worktree or --all"},
+		{"worktree and all", []string{"--worktree", "--all"}, "if any flags in the group [worktree all] are set none of the others can be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeRootCommand(NewMockApp(), append([]string{"--provider", "ollama", "--model=model", "-p=prompt"}, tt.args...)...)
+
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
 func TestRun_WithDynamicPrompt_ShouldUseFromEnv(t *testing.T) {
 	app := NewMockApp()
 	t.Setenv("DIFFAI_PROMPT_5", "dynamic prompt 5")
@@ -348,6 +678,134 @@ func TestRun_WithFilters_ShouldSetDiffFilters(t *testing.T) {
 	app.LLM().(*MockLLMService).AssertExpectations(t)
 }
 
+func TestRun_WithOpenAIOptions_ShouldSetClientOptions(t *testing.T) {
+	app := NewMockApp()
+	t.Setenv("DIFFAI_OPENAI_PROJECT", "proj-env")
+	t.Setenv("DIFFAI_OPENAI_HEADERS", "X-Env=env")
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("openai"), llm.LLMClientOptions{
+			Model: "model",
+			OpenAI: llm.OpenAIOptions{
+				BaseURL:      "http://localhost:8000/v1",
+				Organization: "org-flag",
+				Project:      "proj-env",
+				Headers:      map[string]string{"X-A": "1", "X-B": "2=2"},
+			},
+		}).
+		Return(&MockLLMClient{}, fmt.Errorf("NewClient error"))
+
+	_, err := executeRootCommand(app, "--provider", "openai", "--model=model", "-p=prompt",
+		"--openai-base-url", "http://localhost:8000/v1", "--openai-organization", "org-flag",
+		"--openai-headers", "X-A=1", "--openai-headers", "X-B=2=2")
+	assert.ErrorContains(t, err, "failed to create LLM client")
+	app.LLM().(*MockLLMService).AssertExpectations(t)
+}
+
+func TestRun_WithOpenAIHeadersFromEnv_ShouldSetClientOptions(t *testing.T) {
+	app := NewMockApp()
+	t.Setenv("DIFFAI_OPENAI_HEADERS", "X-A=1, X-B=Bearer token")
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("openai"), llm.LLMClientOptions{
+			Model: "model",
+			OpenAI: llm.OpenAIOptions{
+				Headers: map[string]string{"X-A": "1", "X-B": "Bearer token"},
+			},
+		}).
+		Return(&MockLLMClient{}, fmt.Errorf("NewClient error"))
+
+	_, err := executeRootCommand(app, "--provider", "openai", "--model=model", "-p=prompt")
+	assert.ErrorContains(t, err, "failed to create LLM client")
+	app.LLM().(*MockLLMService).AssertExpectations(t)
+}
+
+func TestRun_WithInvalidOpenAIHeaders_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+	_, err := executeRootCommand(app, "--provider", "openai", "--model=model", "-p=prompt", "--openai-headers", "novalue")
+	assert.ErrorContains(t, err, "invalid openai headers")
+}
+
+func TestRun_WithGenerationOptions_ShouldSetClientOptions(t *testing.T) {
+	app := NewMockApp()
+	t.Setenv("DIFFAI_SEED", "42")
+	t.Setenv("DIFFAI_STOP", "END,STOP")
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	temperature, topP, seed := 0.0, 0.9, int64(42)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), llm.LLMClientOptions{
+			Model: "model",
+			Generation: llm.GenerationOptions{
+				Temperature: &temperature,
+				TopP:        &topP,
+				MaxTokens:   2048,
+				Seed:        &seed,
+				Stop:        []string{"END", "STOP"},
+			},
+		}).
+		Return(&MockLLMClient{}, fmt.Errorf("NewClient error"))
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt",
+		"--temperature", "0", "--top-p", "0.9", "--max-tokens", "2048")
+	assert.ErrorContains(t, err, "failed to create LLM client")
+	app.LLM().(*MockLLMService).AssertExpectations(t)
+}
+
+func TestRun_WithInvalidGenerationOptions_ShouldReturnError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		env         string
+		value       string
+		args        []string
+		expectedErr string
+	}{
+		{
+			name:        "temperature not a number",
+			env:         "DIFFAI_TEMPERATURE",
+			value:       "hot",
+			expectedErr: "invalid DIFFAI_TEMPERATURE 'hot', must be a number",
+		},
+		{
+			name:        "seed not an integer",
+			env:         "DIFFAI_SEED",
+			value:       "1.5",
+			expectedErr: "invalid DIFFAI_SEED '1.5', must be an integer",
+		},
+		{
+			name:        "max tokens not positive",
+			args:        []string{"--max-tokens", "-1"},
+			expectedErr: "invalid DIFFAI_MAX_TOKENS '-1', must be greater than 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := NewMockApp()
+			if tc.env != "" {
+				t.Setenv(tc.env, tc.value)
+			}
+			args := append([]string{"--provider", "ollama", "--model=model", "-p=prompt"}, tc.args...)
+			_, err := executeRootCommand(app, args...)
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func TestRun_WithInteractive_ShouldOpenChatMode(t *testing.T) {
 	app := NewMockApp()
 	app.Git().(*MockGitService).
@@ -363,7 +821,7 @@ func TestRun_WithInteractive_ShouldOpenChatMode(t *testing.T) {
 		Return(&MockLLMClient{}, nil)
 	app.TUI().(*MockTUIService).
 		On("InitialModel", mock.MatchedBy(func(model ui.InitialModelOptions) bool {
-			return model.Title == "fullcommand" && model.Messages[0].Content == "prompt" && model.Messages[1].Content == "diffout"
+			return model.Title == "fullcommand" && model.Context != nil && model.Messages[0].Content == "prompt" && model.Messages[1].Content == "diffout"
 		})).
 		Return(ui.ChatTUIModel{})
 	app.TUI().(*MockTUIService).
@@ -391,6 +849,410 @@ func TestRun_WithDiffTokenLimit_ShouldReturnError(t *testing.T) {
 	app.Git().(*MockGitService).AssertExpectations(t)
 }
 
+func TestRun_WithModelsFile_ShouldDefaultTokenLimitToModel(t *testing.T) {
+	modelsFile := filepath.Join(t.TempDir(), "models.json")
+	os.WriteFile(modelsFile, []byte(`{"models": [{"provider": "ollama", "name": "tiny", "context_window": 20, "max_output_tokens": 10}]}`), 0o600)
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffLongerThanTheModelLimit"),
+			FullCommand: "fullcommand",
+		}, nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=tiny:1b", "-p=prompt", "--models-file", modelsFile)
+
+	assert.ErrorContains(t, err, "diff exceeds estimated token limit of 8 tokens")
+}
+
+func TestRun_WithModelsFile_ShouldAdaptToTheModelFeatures(t *testing.T) {
+	modelsFile := filepath.Join(t.TempDir(), "models.json")
+	os.WriteFile(modelsFile, []byte(`{"models": [{"provider": "ollama", "name": "tiny", "context_window": 1000, "max_output_tokens": 10, "streaming": false, "usage_reporting": false, "system_prompt": false}]}`), 0o600)
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "aires\n").Return("formated res", nil)
+	mockLLMClient.
+		On("Send", mock.Anything, []llm.Message{{Role: llm.User, Content: "prompt\n\ndiffout", Hidden: true}}).
+		Return(&llm.LLMSendResponse{Content: "aires"}, nil)
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "ollama", "--model=tiny", "-p=prompt", "--models-file", modelsFile, "--stream", "--no-cache")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "formated res", output)
+	assert.Contains(t, stderr, "Usage: 5 input, 1 output tokens, ~$0.0000 in ")
+	mockLLMClient.AssertNotCalled(t, "Stream", mock.Anything, mock.Anything)
+}
+
+func TestRun_WithDefaultModelsFile_ShouldLoadIt(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	os.Mkdir(filepath.Join(configDir, "diffai"), 0o700)
+	os.WriteFile(filepath.Join(configDir, "diffai", "models.json"), []byte(`{"models": [`), 0o600)
+
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt")
+
+	assert.ErrorContains(t, err, "failed to load models file: invalid models file")
+}
+
+func TestRun_WithMissingModelsFile_ShouldReturnError(t *testing.T) {
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--models-file", filepath.Join(t.TempDir(), "missing.json"))
+
+	assert.ErrorContains(t, err, "failed to load models file")
+}
+
+const chunkedDiff = "diff --git a/a.go b/a.go\n" +
+	"--- a/a.go\n" +
+	"+++ b/a.go\n" +
+	"@@ -1 +1 @@\n" +
+	"-old a\n" +
+	"+new a\n" +
+	"diff --git a/b.go b/b.go\n" +
+	"--- a/b.go\n" +
+	"+++ b/b.go\n" +
+	"@@ -1 +1 @@\n" +
+	"-old b\n" +
+	"+new b\n"
+
+func messagesContaining(text string) interface{} {
+	return mock.MatchedBy(func(messages []llm.Message) bool {
+		return strings.Contains(messages[len(messages)-1].Content, text)
+	})
+}
+
+func TestRun_WithChunked(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte(chunkedDiff),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 1 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review a"}, nil).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 2 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review b"}, nil).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("## Part 1 (a.go)\n\nreview a\n\n## Part 2 (b.go)\n\nreview b")).
+		Return(&llm.LLMSendResponse{Content: "merged review"}, nil).Once()
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "merged review").Return("[merged review]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--diff-token-limit", "25", "--chunked")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Reviewing 2 parts, 4 at a time")
+	assert.Contains(t, output, "Reviewed part 1/2 (1 files)")
+	assert.Contains(t, output, "Reviewed part 2/2 (1 files)")
+	assert.Contains(t, output, "[merged review]")
+	mockLLMClient.AssertExpectations(t)
+	app.Format().(*MockFormatClient).AssertExpectations(t)
+}
+
+func TestRun_WithChunkedPartError(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte(chunkedDiff),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 1 of 2")).
+		Return(&llm.LLMSendResponse{}, fmt.Errorf("rate limited")).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 2 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review b"}, nil).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("## Part 1 (a.go)\n\nThis part could not be reviewed")).
+		Return(&llm.LLMSendResponse{Content: "merged review"}, nil).Once()
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "merged review").Return("[merged review]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--diff-token-limit", "25", "--chunked", "--concurrency", "1")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Reviewing 2 parts, 1 at a time")
+	assert.Contains(t, output, "Failed to review part 1/2: rate limited")
+	assert.Contains(t, output, "[merged review]")
+	mockLLMClient.AssertExpectations(t)
+}
+
+func TestRun_WithChunkedAllPartsError_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte(chunkedDiff),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{}, fmt.Errorf("rate limited"))
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--diff-token-limit", "25", "--chunked")
+
+	assert.EqualError(t, err, "failed to review part 1/2: rate limited")
+	mockLLMClient.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestRun_WithInvalidConcurrency_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--concurrency", "0")
+
+	assert.EqualError(t, err, "invalid DIFFAI_CONCURRENCY '0', must be greater than 0")
+}
+
+func TestRun_WithTokenizer_ShouldUseItForLimit(t *testing.T) {
+	app := NewMockApp()
+	app.LLM().(*MockLLMService).tokenizer = wordTokenizer{}
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("averyveryverylongsingleworddiff"),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "review"}, nil)
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "review").Return("review", nil)
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--diff-token-limit", "1")
+
+	assert.NoError(t, err, "the diff is a single word for the tokenizer")
+	mockLLMClient.AssertExpectations(t)
+}
+
+func TestRun_WithKnownModel_ShouldReportUsageCost(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte(chunkedDiff),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("openai"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 1 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review a", Usage: llm.LLMTokenUsage{InputTokens: 400, OutputTokens: 100}}, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("part 2 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review b", Usage: llm.LLMTokenUsage{InputTokens: 400, OutputTokens: 100}}, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("split in 2 parts")).
+		Return(&llm.LLMSendResponse{Content: "merged", Usage: llm.LLMTokenUsage{InputTokens: 200, OutputTokens: 300}}, nil)
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "merged").Return("[merged]", nil)
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "openai", "--model=gpt-4.1-2025-04-14", "-p=prompt", "--diff-token-limit", "25", "--chunked")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[merged]", output)
+	assert.Contains(t, stderr, "Usage: 1000 input, 500 output tokens, ~$0.0060 in ")
+}
+
+func TestRun_WithTransientError_ShouldRetry(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{}, &llm.LLMAPIError{StatusCode: 429, Message: "rate limited", RetryAfter: time.Millisecond}).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "review"}, nil).Once()
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "review").Return("[review]", nil)
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "ollama", "--model=model", "-p=prompt")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[review]", output)
+	assert.Contains(t, stderr, "Attempt 1/3 failed (rate limit), retrying in 0.0s: rate limited")
+	mockLLMClient.AssertExpectations(t)
+}
+
+func TestRun_WithMaxAttempts_ShouldStopRetrying(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{}, &llm.LLMAPIError{StatusCode: 503, Message: "unavailable", RetryAfter: time.Millisecond})
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--max-attempts", "2")
+
+	assert.EqualError(t, err, "failed to generate response: unavailable")
+	mockLLMClient.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestRun_WithInvalidMaxAttempts_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--max-attempts", "0")
+
+	assert.EqualError(t, err, "invalid DIFFAI_MAX_ATTEMPTS '0', must be greater than 0")
+}
+
+func TestRun_WithInvalidCacheTTL_ShouldReturnError(t *testing.T) {
+	t.Setenv("DIFFAI_CACHE_TTL", "forever")
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt")
+
+	assert.ErrorContains(t, err, "invalid DIFFAI_CACHE_TTL 'forever', must be greater than 0")
+}
+
+func TestRun_WithFallback_ShouldAnswerWithNextModel(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	primary := MockLLMClient{}
+	secondary := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("openai"), llm.LLMClientOptions{Model: "gpt-4.1"}).
+		Return(&primary, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), llm.LLMClientOptions{Model: "qwen2.5-coder:7b"}).
+		Return(&secondary, nil)
+	primary.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{}, fmt.Errorf("no route to host"))
+	secondary.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "review", Usage: llm.LLMTokenUsage{InputTokens: 10, OutputTokens: 2}}, nil)
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "review").Return("[review]", nil)
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "openai", "--model=gpt-4.1", "-p=prompt", "--fallback", "ollama:qwen2.5-coder:7b")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[review]", output)
+	assert.Contains(t, stderr, "openai:gpt-4.1 failed, falling back to ollama:qwen2.5-coder:7b: no route to host")
+	assert.Contains(t, stderr, "Answered by ollama:qwen2.5-coder:7b")
+	assert.Contains(t, stderr, "Usage: 10 input, 2 output tokens, ~$0.0000 in ")
+}
+
+func TestRun_WithAzureFallback_ShouldUseTheFallbackDeployment(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("diffout"), FullCommand: "fullcommand"}, nil)
+	primary := MockLLMClient{}
+	secondary := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("azure-openai"), mock.MatchedBy(func(opts llm.LLMClientOptions) bool {
+			return opts.Model == "gpt-4o" && opts.Azure.Deployment == "prod"
+		})).
+		Return(&primary, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("azure-openai"), mock.MatchedBy(func(opts llm.LLMClientOptions) bool {
+			return opts.Model == "gpt-4o-mini" && opts.Azure.Deployment == "" && opts.Azure.Endpoint == "https://example.openai.azure.com"
+		})).
+		Return(&secondary, nil)
+	primary.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{}, fmt.Errorf("deployment not found"))
+	secondary.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "review"}, nil)
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "review").Return("[review]", nil)
+
+	output, _, err := executeRootCommandOutputs(app, "--provider", "azure-openai", "--model=gpt-4o", "-p=prompt",
+		"--azure-openai-endpoint", "https://example.openai.azure.com", "--azure-openai-deployment", "prod", "--fallback", "azure-openai:gpt-4o-mini")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[review]", output)
+	app.LLM().(*MockLLMService).AssertExpectations(t)
+}
+
+func TestRun_WithFallbackClientError_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&MockLLMClient{}, nil)
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("anthropic"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&MockLLMClient{}, fmt.Errorf("ANTHROPIC_API_KEY environment variable is not set"))
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--fallback", "anthropic:claude-sonnet-4")
+
+	assert.EqualError(t, err, "failed to create LLM client for fallback anthropic:claude-sonnet-4: ANTHROPIC_API_KEY environment variable is not set")
+}
+
+func TestRun_WithInvalidFallback_ShouldReturnError(t *testing.T) {
+	testCases := []struct {
+		fallback string
+		expected string
+	}{
+		{fallback: "qwen2.5-coder", expected: "invalid fallback 'qwen2.5-coder', expected <provider>:<model>"},
+		{fallback: "ollama:", expected: "invalid fallback 'ollama:', expected <provider>:<model>"},
+		{fallback: "local:qwen", expected: "invalid fallback provider 'local'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fallback, func(t *testing.T) {
+			_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--fallback", tc.fallback)
+
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
 func TestRun_WithEmptyDiff_ShouldReturnError(t *testing.T) {
 	app := NewMockApp()
 	app.Git().(*MockGitService).
@@ -509,53 +1371,240 @@ func TestRun_WithLLMFormatError_ShouldReturnError(t *testing.T) {
 	app.Format().(*MockFormatClient).AssertExpectations(t)
 }
 
-func TestMakeLLMBotResponder_Success(t *testing.T) {
+func newStreamEvents(events ...llm.LLMStreamEvent) <-chan llm.LLMStreamEvent {
+	out := make(chan llm.LLMStreamEvent, len(events))
+	for _, event := range events {
+		out <- event
+	}
+	close(out)
+	return out
+}
+
+func TestRun_WithStream_ShouldWriteBlocksAsTheyComplete(t *testing.T) {
 	app := NewMockApp()
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
 	mockLLMClient := MockLLMClient{}
 	app.LLM().(*MockLLMService).
-		On("NewClient", llm.LLMProvider("ollama"), llm.LLMClientOptions{
-			Model: "model",
-		}).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Stream", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "# Rev"},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "iew\n\nLooks "},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "good"},
+			llm.LLMStreamEvent{
+				Type:    llm.LLMStreamEventTypeComplete,
+				Content: "# Review\n\nLooks good",
+				Usage:   llm.LLMTokenUsage{InputTokens: 12, OutputTokens: 4},
+			},
+		))
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "# Review\n").Return("[title]", nil).Once()
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "Looks good\n").Return("[body]", nil).Once()
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "ollama", "--model=model", "-p=prompt", "--stream")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[title][body]", output)
+	assert.Contains(t, stderr, "Usage: 12 input, 4 output tokens in ")
+	mockLLMClient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	app.Format().(*MockFormatClient).AssertExpectations(t)
+}
+
+func TestRun_WithStreamError_ShouldReturnError(t *testing.T) {
+	app := NewMockApp()
+	t.Setenv("DIFFAI_STREAM", "true")
+	app.Git().(*MockGitService).
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{
+			Out:         []byte("diffout"),
+			FullCommand: "fullcommand",
+		}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.LLM().(*MockLLMService).
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Stream", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "partial\n\n"},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeError, Content: "connection reset"},
+		))
+	app.Format().(*MockFormatClient).
+		On("FormatMarkdown", "partial\n").Return("[partial]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt")
+
+	assert.EqualError(t, err, "failed to generate response: connection reset")
+	assert.Contains(t, output, "[partial]")
+}
+
+const replayDiff = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-old a\n+new a\n"
+
+// newReplayApp answers with the replay provider, through a real client of
+// the transcript.
+func newReplayApp(t *testing.T, transcript string) *MockApp {
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(replayDiff), FullCommand: "fullcommand"}, nil)
+	client, err := llm.NewClient(llm.LLMProviderReplay, llm.LLMClientOptions{Replay: llm.ReplayOptions{File: transcript}})
+	assert.NoError(t, err)
+	app.llm.
+		On("NewClient", llm.LLMProviderReplay, mock.MatchedBy(func(opts llm.LLMClientOptions) bool {
+			return opts.Replay.File == transcript
+		})).
+		Return(client, nil)
+	return app
+}
+
+func TestRun_WithReplay_ShouldAnswerFromTranscript(t *testing.T) {
+	app := newReplayApp(t, "testdata/review_transcript.json")
+	app.format.
+		On("FormatMarkdown", "Renamed `old a` to `new a`.\n").Return("[review]", nil)
+
+	output, stderr, err := executeRootCommandOutputs(app, "--provider", "replay", "--replay-file", "testdata/review_transcript.json", "-p=Review the changes", "--stream")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[review]", output)
+	assert.Contains(t, stderr, "Usage: 42 input, 12 output tokens in ")
+}
+
+func TestRun_WithReplayUnmatchedRequest_ShouldReturnError(t *testing.T) {
+	app := newReplayApp(t, "testdata/review_transcript.json")
+
+	_, err := executeRootCommand(app, "--provider", "replay", "--replay-file", "testdata/review_transcript.json", "-p=Other instructions")
+
+	assert.ErrorContains(t, err, "failed to generate response: no recorded answer in testdata/review_transcript.json")
+}
+
+func TestRun_WithRecord_ShouldReplayRecordedReview(t *testing.T) {
+	transcript := filepath.Join(t.TempDir(), "transcript.json")
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(replayDiff), FullCommand: "fullcommand"}, nil)
+	mockLLMClient := MockLLMClient{}
+	app.llm.
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
 		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, mock.AnythingOfType("[]llm.Message")).
+		Return(&llm.LLMSendResponse{Content: "Looks good", Usage: llm.LLMTokenUsage{InputTokens: 30, OutputTokens: 2}}, nil)
+	app.format.
+		On("FormatMarkdown", "Looks good").Return("[recorded]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--record", transcript)
+	assert.NoError(t, err)
+	assert.Contains(t, output, "[recorded]")
+
+	replayApp := newReplayApp(t, transcript)
+	replayApp.format.
+		On("FormatMarkdown", "Looks good").Return("[replayed]", nil)
+
+	output, stderr, err := executeRootCommandOutputs(replayApp, "--provider", "replay", "--replay-file", transcript, "-p=prompt")
+	assert.NoError(t, err)
+	assert.Equal(t, "[replayed]", output)
+	assert.Contains(t, stderr, "Usage: 30 input, 2 output tokens in ")
+}
+
+func TestRun_WithMockProvider_ShouldAnswerWithoutModel(t *testing.T) {
+	t.Setenv("DIFFAI_MOCK_DELAY", "1ms")
+	mockOpts := llm.MockOptions{Response: "{{.Files}} file, +{{.Additions}} -{{.Deletions}} by {{.Model}}", ChunkDelay: time.Millisecond}
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(replayDiff), FullCommand: "fullcommand"}, nil)
+	client, err := llm.NewClient(llm.LLMProviderMock, llm.LLMClientOptions{Mock: mockOpts})
+	assert.NoError(t, err)
+	app.llm.
+		On("NewClient", llm.LLMProviderMock, llm.LLMClientOptions{Mock: mockOpts}).
+		Return(client, nil)
+	app.format.
+		On("FormatMarkdown", "1 file, +1 -1 by mock\n").Return("[mock review]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "mock", "-p=prompt", "--stream", "--mock-response", mockOpts.Response)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "[mock review]")
+	app.llm.AssertExpectations(t)
+}
+
+func TestRun_WithMockProviderError_ShouldReturnError(t *testing.T) {
+	mockOpts := llm.MockOptions{ErrorStatus: 401}
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffStaged", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(replayDiff), FullCommand: "fullcommand"}, nil)
+	client, err := llm.NewClient(llm.LLMProviderMock, llm.LLMClientOptions{Mock: mockOpts})
+	assert.NoError(t, err)
+	app.llm.
+		On("NewClient", llm.LLMProviderMock, llm.LLMClientOptions{Mock: mockOpts}).
+		Return(client, nil)
+
+	_, err = executeRootCommand(app, "--provider", "mock", "-p=prompt", "--mock-error-status", "401")
+
+	assert.EqualError(t, err, "failed to generate response: mock error 401 Unauthorized")
+}
+
+func TestMakeLLMBotResponder_Success(t *testing.T) {
+	mockLLMClient := MockLLMClient{}
 	messages := []llm.Message{
 		{Role: llm.User, Content: "What is Go?"},
 	}
-	expectedResponse := "Go is a statically typed programming language."
 
 	mockLLMClient.
-		On("Send", t.Context(), messages).
-		Return(&llm.LLMSendResponse{Content: expectedResponse}, nil)
+		On("Stream", t.Context(), messages).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "Go is "},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "a language."},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeComplete, Usage: llm.LLMTokenUsage{InputTokens: 3, OutputTokens: 5}},
+		))
 
-	responder := makeLLMBotResponder(&mockLLMClient, t.Context())
-	cmd := responder(messages)
+	responder := makeLLMBotResponder(&mockLLMClient)
+	cmd := responder(t.Context(), messages)
 
 	msg := cmd()
-
-	llmMsg, _ := msg.(llm.Message)
+	chunk, ok := msg.(ui.ChatStreamChunk)
+	assert.True(t, ok)
+	assert.Equal(t, "Go is ", chunk.Content)
+
+	msg = chunk.Next()
+	chunk, ok = msg.(ui.ChatStreamChunk)
+	assert.True(t, ok)
+	assert.Equal(t, "a language.", chunk.Content)
+
+	msg = chunk.Next()
+	llmMsg, ok := msg.(llm.Message)
+	assert.True(t, ok)
 	assert.Equal(t, llm.Assistant, llmMsg.Role)
-	assert.Equal(t, expectedResponse, llmMsg.Content)
+	assert.Equal(t, "Go is a language.", llmMsg.Content)
+	assert.Equal(t, llm.LLMTokenUsage{InputTokens: 3, OutputTokens: 5}, llmMsg.Usage)
 
 	mockLLMClient.AssertExpectations(t)
 }
 
 func TestMakeLLMBotResponder_Error(t *testing.T) {
-	app := NewMockApp()
 	mockLLMClient := MockLLMClient{}
-	app.LLM().(*MockLLMService).
-		On("NewClient", llm.LLMProvider("ollama"), llm.LLMClientOptions{
-			Model: "model",
-		}).
-		Return(&mockLLMClient, nil)
 	messages := []llm.Message{
 		{Role: llm.User, Content: "What is Go?"},
 	}
 
 	mockLLMClient.
-		On("Send", t.Context(), messages).
-		Return(&llm.LLMSendResponse{}, errors.New("Send error"))
+		On("Stream", t.Context(), messages).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeError, Content: "Stream error"},
+		))
 
-	responder := makeLLMBotResponder(&mockLLMClient, t.Context())
-	cmd := responder(messages)
+	responder := makeLLMBotResponder(&mockLLMClient)
+	cmd := responder(t.Context(), messages)
 
 	msg := cmd()
 
@@ -566,6 +1615,53 @@ func TestMakeLLMBotResponder_Error(t *testing.T) {
 	mockLLMClient.AssertExpectations(t)
 }
 
+func TestMakeLLMBotResponder_ErrorAfterContent(t *testing.T) {
+	mockLLMClient := MockLLMClient{}
+	messages := []llm.Message{
+		{Role: llm.User, Content: "What is Go?"},
+	}
+
+	mockLLMClient.
+		On("Stream", t.Context(), messages).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "Go is"},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeError, Content: "connection reset"},
+		))
+
+	responder := makeLLMBotResponder(&mockLLMClient)
+	chunk := responder(t.Context(), messages)().(ui.ChatStreamChunk)
+
+	llmMsg, _ := chunk.Next().(llm.Message)
+	assert.Equal(t, "Go is\n\nFailed to generate response: connection reset", llmMsg.Content)
+	assert.False(t, llmMsg.Interrupted)
+
+	mockLLMClient.AssertExpectations(t)
+}
+
+func TestMakeLLMBotResponder_Cancelled(t *testing.T) {
+	mockLLMClient := MockLLMClient{}
+	messages := []llm.Message{
+		{Role: llm.User, Content: "What is Go?"},
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+
+	mockLLMClient.
+		On("Stream", ctx, messages).
+		Return(newStreamEvents(
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeMessage, Content: "Go is"},
+			llm.LLMStreamEvent{Type: llm.LLMStreamEventTypeError, Content: "context canceled"},
+		))
+
+	responder := makeLLMBotResponder(&mockLLMClient)
+	chunk := responder(ctx, messages)().(ui.ChatStreamChunk)
+	cancel()
+
+	llmMsg, _ := chunk.Next().(llm.Message)
+	assert.Equal(t, llm.Message{Role: llm.Assistant, Content: "Go is", Interrupted: true}, llmMsg)
+
+	mockLLMClient.AssertExpectations(t)
+}
+
 // func TestMakeLLMBotResponder_Error(t *testing.T) {
 // 	mockClient := new(MockLLMClient)
 // 	ctx := context.Background()
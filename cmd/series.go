@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/app"
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/klemjul/diffai/internal/review"
+	"github.com/spf13/cobra"
+)
+
+// seriesRequests builds the requests reviewing each commit of a series on
+// its own diff, skipping the commits without changes like most merges. The
+// commits reviewed are returned along their requests.
+func seriesRequests(app app.App, tokenizer llm.Tokenizer, prompt string, commits []git.Commit, options git.DiffOptions, diffTokenLimit int) ([]git.Commit, [][]llm.Message, error) {
+	var reviewed []git.Commit
+	var diffs []string
+	for _, commit := range commits {
+		diffRes, err := app.Git().DiffCommit(commit.Hash, options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error generating diff of commit %s: %v", commit.Hash, err)
+		}
+		files, err := diffRes.Files()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse diff of commit %s: %v", commit.Hash, err)
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		// the commit header of git show is described by the request already
+		var diff strings.Builder
+		for _, file := range files {
+			diff.WriteString(file.Raw)
+		}
+		if tokenizer.CountTokens(diff.String()) > diffTokenLimit {
+			return nil, nil, fmt.Errorf("diff of commit %s exceeds estimated token limit of %d tokens. Please reduce the range or extend token limit", commit.Hash, diffTokenLimit)
+		}
+		reviewed = append(reviewed, commit)
+		diffs = append(diffs, diff.String())
+	}
+	if len(reviewed) == 0 {
+		return nil, nil, fmt.Errorf("no commit with changes found in the range")
+	}
+
+	requests := make([][]llm.Message, len(reviewed))
+	for i, commit := range reviewed {
+		requests[i] = review.CommitMessages(prompt, commit, diffs[i], i, len(reviewed))
+	}
+	return reviewed, requests, nil
+}
+
+// reviewSeries reviews each commit of a series on its own and returns the
+// messages asking to summarize the series from their reviews, which then go
+// through the usual output modes like a chunked review.
+func reviewSeries(cmd *cobra.Command, client llm.LLMClient, prompt string, commits []git.Commit, requests [][]llm.Message, concurrency int, usage *reviewUsage) ([]llm.Message, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "Reviewing %d commits, %d at a time\n", len(commits), concurrency)
+	reviews, err := sendReviews(cmd, client, requests, concurrency, usage,
+		func(i int) string { return fmt.Sprintf("commit %d/%d", i+1, len(commits)) },
+		func(i int) string { return fmt.Sprintf(" (%s)", commits[i].Subject) })
+	if err != nil {
+		return nil, err
+	}
+	return review.SeriesMessages(prompt, commits, reviews), nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newSeriesApp returns an app reviewing main..dev, made of a commit on a.go,
+// a merge without changes and a commit on b.go.
+func newSeriesApp() *MockApp {
+	app := NewMockApp().(*MockApp)
+	app.git.
+		On("DiffRefs", "main", "dev", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(chunkedDiff), FullCommand: "git diff main dev"}, nil)
+	app.git.
+		On("LogRefs", "main", "dev", mock.AnythingOfType("git.DiffOptions")).
+		Return([]git.Commit{
+			{Hash: "aaa", Subject: "Change a"},
+			{Hash: "mmm", Subject: "Merge main"},
+			{Hash: "bbb", Subject: "Change b"},
+		}, nil)
+	app.git.
+		On("DiffCommit", "aaa", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("commit aaa\n\n    Change a\n\n" + chunkedDiff[:strings.Index(chunkedDiff, "diff --git a/b.go")])}, nil)
+	app.git.
+		On("DiffCommit", "mmm", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte("commit mmm\nMerge: aaa ccc\n\n    Merge main\n")}, nil)
+	app.git.
+		On("DiffCommit", "bbb", mock.AnythingOfType("git.DiffOptions")).
+		Return(git.DiffResult{Out: []byte(chunkedDiff[strings.Index(chunkedDiff, "diff --git a/b.go"):])}, nil)
+	return app
+}
+
+func TestRun_WithSeries(t *testing.T) {
+	app := newSeriesApp()
+	mockLLMClient := MockLLMClient{}
+	app.llm.
+		On("NewClient", llm.LLMProvider("ollama"), mock.AnythingOfType("llm.LLMClientOptions")).
+		Return(&mockLLMClient, nil)
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("### aaa Change a\n\nAuthor: \nDate: \n\n## Diff\n\ndiff --git a/a.go b/a.go\n")).
+		Return(&llm.LLMSendResponse{Content: "review a"}, nil).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("commit 2 of 2")).
+		Return(&llm.LLMSendResponse{Content: "review b"}, nil).Once()
+	mockLLMClient.
+		On("Send", mock.Anything, messagesContaining("## Commit 1 aaa Change a\n\nreview a\n\n## Commit 2 bbb Change b\n\nreview b")).
+		Return(&llm.LLMSendResponse{Content: "series review"}, nil).Once()
+	app.format.
+		On("FormatMarkdown", "series review").Return("[series review]", nil)
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--series", "main..dev")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Reviewing 2 commits, 4 at a time")
+	assert.Contains(t, output, "Reviewed commit 1/2 (Change a)")
+	assert.Contains(t, output, "Reviewed commit 2/2 (Change b)")
+	assert.Contains(t, output, "[series review]")
+	mockLLMClient.AssertExpectations(t)
+}
+
+func TestRun_WithSeriesDryRun_ShouldPrintCommitRequests(t *testing.T) {
+	app := newSeriesApp()
+
+	output, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--series", "main", "dev", "--dry-run", "--dry-run-format", "json")
+
+	require.NoError(t, err)
+	var report dryRunReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.True(t, report.Series)
+	require.Len(t, report.Requests, 2)
+	assert.Contains(t, report.Requests[0].Messages[1].Content, "This is commit 1 of 2")
+	assert.NotContains(t, report.Requests[0].Messages[1].Content, "commit aaa")
+	assert.Contains(t, report.Requests[1].Messages[1].Content, "### bbb Change b")
+	app.llm.AssertNotCalled(t, "NewClient", mock.Anything, mock.Anything)
+}
+
+func TestRun_WithSeriesTooLargeCommit_ShouldReturnError(t *testing.T) {
+	app := newSeriesApp()
+
+	_, err := executeRootCommand(app, "--provider", "ollama", "--model=model", "-p=prompt", "--series", "main..dev", "--diff-token-limit", "5", "--dry-run")
+
+	assert.EqualError(t, err, "diff of commit aaa exceeds estimated token limit of 5 tokens. Please reduce the range or extend token limit")
+}
+
+func TestRun_WithSeriesWithoutRange_ShouldReturnError(t *testing.T) {
+	_, err := executeRootCommand(NewMockApp(), "--provider", "ollama", "--model=model", "-p=prompt", "--series", "abc")
+
+	assert.EqualError(t, err, "--series reviews a range of commits, like main..feature")
+}
@@ -10,8 +10,13 @@ import (
 
 type GitService interface {
 	DiffStaged(diffOptions git.DiffOptions) (git.DiffResult, error)
+	DiffWorktree(diffOptions git.DiffOptions) (git.DiffResult, error)
+	DiffAll(diffOptions git.DiffOptions) (git.DiffResult, error)
 	DiffRefs(refFrom string, refTo string, diffOptions git.DiffOptions) (git.DiffResult, error)
 	DiffCommit(ref string, diffOptions git.DiffOptions) (git.DiffResult, error)
+	LogRefs(refFrom string, refTo string, diffOptions git.DiffOptions) ([]git.Commit, error)
+	LogCommit(ref string, diffOptions git.DiffOptions) ([]git.Commit, error)
+	ReadFile(ref string, path string, diffOptions git.DiffOptions) ([]byte, error)
 }
 
 type TUIService interface {
@@ -21,6 +26,7 @@ type TUIService interface {
 
 type LLMService interface {
 	NewClient(provider llm.LLMProvider, opts llm.LLMClientOptions) (llm.LLMClient, error)
+	NewTokenizer(model string) llm.Tokenizer
 }
 
 type TextFormatService interface {
@@ -57,12 +63,27 @@ func (a *DefaultApp) Format() TextFormatService { return a.format }
 func (g *DefaultGitService) DiffStaged(diffOptions git.DiffOptions) (git.DiffResult, error) {
 	return git.DiffStaged(diffOptions)
 }
+func (g *DefaultGitService) DiffWorktree(diffOptions git.DiffOptions) (git.DiffResult, error) {
+	return git.DiffWorktree(diffOptions)
+}
+func (g *DefaultGitService) DiffAll(diffOptions git.DiffOptions) (git.DiffResult, error) {
+	return git.DiffAll(diffOptions)
+}
 func (g *DefaultGitService) DiffRefs(refFrom string, refTo string, diffOptions git.DiffOptions) (git.DiffResult, error) {
 	return git.DiffRefs(refFrom, refTo, diffOptions)
 }
 func (g *DefaultGitService) DiffCommit(ref string, diffOptions git.DiffOptions) (git.DiffResult, error) {
 	return git.DiffCommit(ref, diffOptions)
 }
+func (g *DefaultGitService) LogRefs(refFrom string, refTo string, diffOptions git.DiffOptions) ([]git.Commit, error) {
+	return git.LogRefs(refFrom, refTo, diffOptions)
+}
+func (g *DefaultGitService) LogCommit(ref string, diffOptions git.DiffOptions) ([]git.Commit, error) {
+	return git.LogCommit(ref, diffOptions)
+}
+func (g *DefaultGitService) ReadFile(ref string, path string, diffOptions git.DiffOptions) ([]byte, error) {
+	return git.ReadFile(ref, path, diffOptions)
+}
 
 func (c *DefaultTUIService) InitialModel(opts ui.InitialModelOptions) ui.ChatTUIModel {
 	return ui.InitialModel(opts)
@@ -74,6 +95,9 @@ func (c *DefaultTUIService) Run(model ui.ChatTUIModel) (returnModel tea.Model, r
 func (l *DefaultLLMService) NewClient(provider llm.LLMProvider, opts llm.LLMClientOptions) (llm.LLMClient, error) {
 	return llm.NewClient(provider, opts)
 }
+func (l *DefaultLLMService) NewTokenizer(model string) llm.Tokenizer {
+	return llm.NewTokenizer(model)
+}
 
 func (l *DefaultTextFormatService) FormatMarkdown(text string) (string, error) {
 	return format.FormatMarkdown(text)
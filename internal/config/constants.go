@@ -1,14 +1,59 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	DEFAULT_DIFF_TOKEN_LIMIT = 100_000
+	DEFAULT_CONCURRENCY      = 4
+	DEFAULT_MAX_ATTEMPTS     = 3
+	DEFAULT_CACHE_TTL        = 7 * 24 * time.Hour
+	DEFAULT_CONTEXT_LINES    = 3
 	ENV_PREFIX               = "DIFFAI"
 	ENV_DIFF_TOKEN_LIMIT     = "DIFF_TOKEN_LIMIT"
+	ENV_CHUNKED              = "CHUNKED"
+	ENV_CONCURRENCY          = "CONCURRENCY"
+	ENV_MODELS_FILE          = "MODELS_FILE"
+	ENV_MAX_ATTEMPTS         = "MAX_ATTEMPTS"
+	ENV_FALLBACK             = "FALLBACK"
+	ENV_NO_CACHE             = "NO_CACHE"
+	ENV_CACHE_TTL            = "CACHE_TTL"
+	ENV_RECORD               = "RECORD"
+	ENV_REPLAY_FILE          = "REPLAY_FILE"
+	ENV_MOCK_RESPONSE        = "MOCK_RESPONSE"
+	ENV_MOCK_RESPONSE_FILE   = "MOCK_RESPONSE_FILE"
+	ENV_MOCK_DELAY           = "MOCK_DELAY"
+	ENV_MOCK_ERROR_STATUS    = "MOCK_ERROR_STATUS"
+	ENV_MOCK_FAILURES        = "MOCK_FAILURES"
+	ENV_DRY_RUN              = "DRY_RUN"
+	ENV_MERGE_BASE           = "MERGE_BASE"
+	ENV_COMMIT_LOG           = "COMMIT_LOG"
+	ENV_SERIES               = "SERIES"
+	ENV_WORKTREE             = "WORKTREE"
+	ENV_ALL                  = "ALL"
+	ENV_UNTRACKED            = "UNTRACKED"
+	ENV_CONTEXT_LINES        = "CONTEXT_LINES"
+	ENV_FUNCTION_CONTEXT     = "FUNCTION_CONTEXT"
+	ENV_FULL_FILES           = "FULL_FILES"
+	ENV_DRY_RUN_FORMAT       = "DRY_RUN_FORMAT"
 	ENV_MODEL                = "MODEL"
 	ENV_PROVIDER             = "PROVIDER"
 	ENV_PROMPT               = "PROMPT"
+	ENV_TEMPERATURE          = "TEMPERATURE"
+	ENV_TOP_P                = "TOP_P"
+	ENV_MAX_TOKENS           = "MAX_TOKENS"
+	ENV_SEED                 = "SEED"
+	ENV_STOP                 = "STOP"
+	ENV_STREAM               = "STREAM"
+	ENV_OPENAI_BASE_URL      = "OPENAI_BASE_URL"
+	ENV_OPENAI_ORGANIZATION  = "OPENAI_ORGANIZATION"
+	ENV_OPENAI_PROJECT       = "OPENAI_PROJECT"
+	ENV_OPENAI_HEADERS       = "OPENAI_HEADERS"
+	ENV_AZURE_ENDPOINT       = "AZURE_OPENAI_ENDPOINT"
+	ENV_AZURE_DEPLOYMENT     = "AZURE_OPENAI_DEPLOYMENT"
+	ENV_AZURE_API_VERSION    = "AZURE_OPENAI_API_VERSION"
 )
 
 func GetEnvWithPrefix(env string) string {
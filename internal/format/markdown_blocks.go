@@ -0,0 +1,66 @@
+package format
+
+import "strings"
+
+// MarkdownBlockBuffer accumulates streamed markdown and releases it one
+// top-level block at a time, so each block can be rendered as soon as it is
+// complete. Blank lines inside fenced code blocks don't end a block.
+type MarkdownBlockBuffer struct {
+	partialLine string
+	block       []string
+	fence       string
+}
+
+// Write appends a streamed chunk and returns the blocks it completed.
+func (b *MarkdownBlockBuffer) Write(chunk string) []string {
+	var blocks []string
+	text := b.partialLine + chunk
+	for {
+		line, rest, found := strings.Cut(text, "\n")
+		if !found {
+			b.partialLine = text
+			return blocks
+		}
+		text = rest
+		if block, done := b.addLine(line); done {
+			blocks = append(blocks, block)
+		}
+	}
+}
+
+// Flush returns whatever is left once the stream is over.
+func (b *MarkdownBlockBuffer) Flush() string {
+	if b.partialLine != "" {
+		b.block = append(b.block, b.partialLine)
+		b.partialLine = ""
+	}
+	block := strings.Join(b.block, "\n")
+	b.block = nil
+	b.fence = ""
+	if strings.TrimSpace(block) == "" {
+		return ""
+	}
+	return block + "\n"
+}
+
+func (b *MarkdownBlockBuffer) addLine(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	if b.fence == "" && trimmed == "" {
+		if len(b.block) == 0 {
+			return "", false
+		}
+		block := strings.Join(b.block, "\n") + "\n"
+		b.block = nil
+		return block, true
+	}
+
+	b.block = append(b.block, line)
+	switch {
+	case b.fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+		b.fence = trimmed[:3]
+	case b.fence != "" && strings.HasPrefix(trimmed, b.fence) && strings.Trim(trimmed, b.fence[:1]) == "":
+		b.fence = ""
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownBlockBuffer(t *testing.T) {
+	tests := []struct {
+		name           string
+		chunks         []string
+		expectedBlocks []string
+		expectedFlush  string
+	}{
+		{
+			name:           "single paragraph kept until flush",
+			chunks:         []string{"Hello ", "world"},
+			expectedBlocks: nil,
+			expectedFlush:  "Hello world\n",
+		},
+		{
+			name:           "blank line completes a block",
+			chunks:         []string{"# Title\n", "\nSome", " text\n\nMore"},
+			expectedBlocks: []string{"# Title\n", "Some text\n"},
+			expectedFlush:  "More\n",
+		},
+		{
+			name:           "chunk boundaries inside newlines",
+			chunks:         []string{"a\n", "\n", "\n", "b\n\n"},
+			expectedBlocks: []string{"a\n", "b\n"},
+			expectedFlush:  "",
+		},
+		{
+			name:           "blank lines inside code fence",
+			chunks:         []string{"```go\nfunc a() {}\n\nfunc b() {}\n```\n\nafter"},
+			expectedBlocks: []string{"```go\nfunc a() {}\n\nfunc b() {}\n```\n"},
+			expectedFlush:  "after\n",
+		},
+		{
+			name:           "unterminated code fence is flushed",
+			chunks:         []string{"~~~\ncode\n\nmore code"},
+			expectedBlocks: nil,
+			expectedFlush:  "~~~\ncode\n\nmore code\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := MarkdownBlockBuffer{}
+			var blocks []string
+			for _, chunk := range tt.chunks {
+				blocks = append(blocks, buffer.Write(chunk)...)
+			}
+			assert.Equal(t, tt.expectedBlocks, blocks)
+			assert.Equal(t, tt.expectedFlush, buffer.Flush())
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 )
 
@@ -14,7 +17,16 @@ type DiffOptions struct {
 	CliWd       string
 	Unified     int
 	FindRenames bool
-	Filters     []string
+	// FunctionContext shows the whole function around each change as
+	// context, on top of the Unified lines.
+	FunctionContext bool
+	Filters         []string
+	// Untracked adds the files git doesn't track yet as new files to the
+	// working tree diffs.
+	Untracked bool
+	// MergeBase diffs refs from their merge base, like refFrom...refTo, to
+	// only keep the changes of refTo.
+	MergeBase bool
 }
 
 type DiffResult struct {
@@ -27,8 +39,24 @@ func DiffStaged(diffOptions DiffOptions) (DiffResult, error) {
 	return runCli(diffOptions.CliPath, diffOptions.CliWd, args...)
 }
 
+// DiffWorktree diffs the changes of the working tree not staged yet.
+func DiffWorktree(diffOptions DiffOptions) (DiffResult, error) {
+	args := buildGenericArgs([]string{"diff"}, diffOptions)
+	return diffWithUntracked(args, diffOptions)
+}
+
+// DiffAll diffs both the staged and unstaged changes against HEAD.
+func DiffAll(diffOptions DiffOptions) (DiffResult, error) {
+	args := buildGenericArgs([]string{"diff", "HEAD"}, diffOptions)
+	return diffWithUntracked(args, diffOptions)
+}
+
 func DiffRefs(refFrom string, refTo string, diffOptions DiffOptions) (DiffResult, error) {
-	args := buildGenericArgs([]string{"diff", refFrom, refTo}, diffOptions)
+	base := []string{"diff", refFrom, refTo}
+	if diffOptions.MergeBase {
+		base = []string{"diff", refFrom + "..." + refTo}
+	}
+	args := buildGenericArgs(base, diffOptions)
 	return runCli(diffOptions.CliPath, diffOptions.CliWd, args...)
 }
 
@@ -37,6 +65,51 @@ func DiffCommit(ref string, diffOptions DiffOptions) (DiffResult, error) {
 	return runCli(diffOptions.CliPath, diffOptions.CliWd, args...)
 }
 
+// diffWithUntracked runs the diff of args, followed by a new file diff of
+// every untracked file matching the filters when asked to.
+func diffWithUntracked(args []string, diffOptions DiffOptions) (DiffResult, error) {
+	res, err := runCli(diffOptions.CliPath, diffOptions.CliWd, args...)
+	if err != nil || !diffOptions.Untracked {
+		return res, err
+	}
+
+	// the paths are relative to the repository root like the ones of the
+	// diff, and so are the no-index diffs run from there
+	listArgs := []string{"ls-files", "--others", "--exclude-standard", "--full-name", "-z"}
+	if len(diffOptions.Filters) > 0 {
+		listArgs = append(append(listArgs, "--"), diffOptions.Filters...)
+	}
+	list, err := runCli(diffOptions.CliPath, diffOptions.CliWd, listArgs...)
+	if err != nil {
+		return list, err
+	}
+
+	root, err := topLevel(diffOptions)
+	if err != nil {
+		return res, err
+	}
+
+	untracked := 0
+	for _, path := range strings.Split(string(list.Out), "\x00") {
+		if path == "" {
+			continue
+		}
+		fileDiff, err := runCli(diffOptions.CliPath, root,
+			"diff", "--no-index", fmt.Sprintf("--unified=%v", diffOptions.Unified), "--", os.DevNull, path)
+		// --no-index exits with 1 when the files differ, which they always do
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return fileDiff, err
+		}
+		res.Out = append(res.Out, fileDiff.Out...)
+		untracked++
+	}
+	if untracked > 0 {
+		res.FullCommand += fmt.Sprintf(" + %d untracked files", untracked)
+	}
+	return res, nil
+}
+
 func runCli(cliPath string, dirName string, args ...string) (DiffResult, error) {
 	cmd := execCommander(cliPath, args...)
 	cmd.SetDir(dirName)
@@ -57,6 +130,10 @@ func buildGenericArgs(base []string, options DiffOptions) []string {
 		args = append(args, "--find-renames")
 	}
 
+	if options.FunctionContext {
+		args = append(args, "--function-context")
+	}
+
 	if len(options.Filters) > 0 {
 		args = append(args, "--")
 		args = append(args, options.Filters...)
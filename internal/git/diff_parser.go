@@ -0,0 +1,375 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type FileStatus string
+
+const (
+	FileStatusModified FileStatus = "modified"
+	FileStatusAdded    FileStatus = "added"
+	FileStatusDeleted  FileStatus = "deleted"
+	FileStatusRenamed  FileStatus = "renamed"
+	FileStatusCopied   FileStatus = "copied"
+)
+
+// FileModeSymlink and FileModeGitlink are the modes of the entries that
+// aren't regular files, a symbolic link and a submodule.
+const (
+	FileModeSymlink = "120000"
+	FileModeGitlink = "160000"
+)
+
+type LineType string
+
+const (
+	LineContext LineType = "context"
+	LineAdded   LineType = "added"
+	LineDeleted LineType = "deleted"
+)
+
+// Line is a single line of a hunk. OldNumber and NewNumber are 0 on the side
+// where the line doesn't exist.
+type Line struct {
+	Type      LineType
+	Content   string
+	OldNumber int
+	NewNumber int
+	// NoNewlineAtEOF is set on the last line of a file missing its final
+	// newline.
+	NoNewlineAtEOF bool
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	// Section is the function or heading git prints after the line ranges.
+	Section string
+	Lines   []Line
+}
+
+// FileDiff is the change of a single file. Paths are empty on the side where
+// the file doesn't exist, Raw keeps the file section as printed by git.
+type FileDiff struct {
+	OldPath    string
+	NewPath    string
+	Status     FileStatus
+	OldMode    string
+	NewMode    string
+	Similarity int
+	Binary     bool
+	// Combined is set for merge commits shown in the combined diff format,
+	// their hunks are not parsed.
+	Combined bool
+	Hunks    []Hunk
+	Raw      string
+}
+
+// Path returns the path of the file after the change, or before it when the
+// file was deleted.
+func (f FileDiff) Path() string {
+	if f.Status == FileStatusDeleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// Regular tells whether the file is a regular file on both sides of the
+// change, not a symbolic link or a submodule.
+func (f FileDiff) Regular() bool {
+	for _, mode := range []string{f.OldMode, f.NewMode} {
+		if mode == FileModeSymlink || mode == FileModeGitlink {
+			return false
+		}
+	}
+	return true
+}
+
+func (f FileDiff) Additions() int {
+	return f.countLines(LineAdded)
+}
+
+func (f FileDiff) Deletions() int {
+	return f.countLines(LineDeleted)
+}
+
+func (f FileDiff) countLines(lineType LineType) int {
+	count := 0
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Type == lineType {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Files parses the diff output into one FileDiff per changed file.
+func (r DiffResult) Files() ([]FileDiff, error) {
+	return ParseDiff(r.Out)
+}
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// ParseDiff parses unified diff output of git diff or git show. Anything
+// before the first file, like the commit header of git show, is ignored.
+func ParseDiff(out []byte) ([]FileDiff, error) {
+	p := diffParser{}
+	for _, raw := range strings.SplitAfter(string(out), "\n") {
+		if raw == "" {
+			continue
+		}
+		if err := p.parseLine(strings.TrimSuffix(raw, "\n")); err != nil {
+			return nil, err
+		}
+		if p.file != nil {
+			p.raw.WriteString(raw)
+		}
+	}
+	if p.inHunk() {
+		return nil, fmt.Errorf("truncated hunk in %s", p.file.Path())
+	}
+	p.endFile()
+	return p.files, nil
+}
+
+type diffParser struct {
+	files []FileDiff
+	file  *FileDiff
+	hunk  *Hunk
+	raw   strings.Builder
+
+	oldLine int
+	newLine int
+	oldLeft int
+	newLeft int
+}
+
+func (p *diffParser) inHunk() bool {
+	return p.hunk != nil && (p.oldLeft > 0 || p.newLeft > 0)
+}
+
+func (p *diffParser) parseLine(line string) error {
+	if p.inHunk() {
+		return p.parseHunkLine(line)
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		oldPath, newPath := splitHeaderPaths(strings.TrimPrefix(line, "diff --git "))
+		p.startFile(FileDiff{
+			OldPath: trimPathPrefix(oldPath),
+			NewPath: trimPathPrefix(newPath),
+		})
+	case strings.HasPrefix(line, "diff --cc "), strings.HasPrefix(line, "diff --combined "):
+		_, path, _ := strings.Cut(line[len("diff --"):], " ")
+		path = unquotePath(path)
+		p.startFile(FileDiff{OldPath: path, NewPath: path, Combined: true})
+	case p.file == nil, p.file.Combined:
+		return nil
+	case strings.HasPrefix(line, "@@ "):
+		return p.startHunk(line)
+	case strings.HasPrefix(line, `\`):
+		p.markNoNewline()
+	default:
+		p.parseHeader(line)
+	}
+	return nil
+}
+
+func (p *diffParser) startFile(file FileDiff) {
+	p.endFile()
+	file.Status = FileStatusModified
+	p.file = &file
+}
+
+func (p *diffParser) endFile() {
+	if p.file == nil {
+		return
+	}
+	switch p.file.Status {
+	case FileStatusAdded:
+		p.file.OldPath = ""
+	case FileStatusDeleted:
+		p.file.NewPath = ""
+	}
+	p.file.Raw = p.raw.String()
+	p.files = append(p.files, *p.file)
+	p.file = nil
+	p.hunk = nil
+	p.raw.Reset()
+}
+
+func (p *diffParser) parseHeader(line string) {
+	file := p.file
+	switch {
+	case strings.HasPrefix(line, "old mode "):
+		file.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		file.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		file.Status = FileStatusDeleted
+		file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+	case strings.HasPrefix(line, "new file mode "):
+		file.Status = FileStatusAdded
+		file.NewMode = strings.TrimPrefix(line, "new file mode ")
+	case strings.HasPrefix(line, "similarity index "):
+		file.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+	case strings.HasPrefix(line, "rename from "):
+		file.Status = FileStatusRenamed
+		file.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		file.NewPath = unquotePath(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy from "):
+		file.Status = FileStatusCopied
+		file.OldPath = unquotePath(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		file.NewPath = unquotePath(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "index "):
+		// The mode is only printed here when it didn't change.
+		if _, mode, found := strings.Cut(strings.TrimPrefix(line, "index "), " "); found {
+			file.OldMode, file.NewMode = mode, mode
+		}
+	case strings.HasPrefix(line, "--- "):
+		file.OldPath = parseMarkerPath(strings.TrimPrefix(line, "--- "))
+	case strings.HasPrefix(line, "+++ "):
+		file.NewPath = parseMarkerPath(strings.TrimPrefix(line, "+++ "))
+	case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+		file.Binary = true
+	}
+}
+
+func (p *diffParser) startHunk(line string) error {
+	match := hunkHeaderRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return fmt.Errorf("invalid hunk header in %s: %q", p.file.Path(), line)
+	}
+
+	hunk := Hunk{
+		OldStart: atoiOr(match[1], 0),
+		OldLines: atoiOr(match[2], 1),
+		NewStart: atoiOr(match[3], 0),
+		NewLines: atoiOr(match[4], 1),
+		Section:  match[5],
+	}
+	p.file.Hunks = append(p.file.Hunks, hunk)
+	p.hunk = &p.file.Hunks[len(p.file.Hunks)-1]
+	p.oldLine, p.newLine = hunk.OldStart, hunk.NewStart
+	p.oldLeft, p.newLeft = hunk.OldLines, hunk.NewLines
+	return nil
+}
+
+func (p *diffParser) parseHunkLine(line string) error {
+	// Some tools strip the leading space of empty context lines.
+	if line == "" {
+		line = " "
+	}
+
+	switch line[0] {
+	case ' ':
+		if p.oldLeft == 0 || p.newLeft == 0 {
+			return p.hunkError(line)
+		}
+		p.hunk.Lines = append(p.hunk.Lines, Line{Type: LineContext, Content: line[1:], OldNumber: p.oldLine, NewNumber: p.newLine})
+		p.oldLine++
+		p.newLine++
+		p.oldLeft--
+		p.newLeft--
+	case '-':
+		if p.oldLeft == 0 {
+			return p.hunkError(line)
+		}
+		p.hunk.Lines = append(p.hunk.Lines, Line{Type: LineDeleted, Content: line[1:], OldNumber: p.oldLine})
+		p.oldLine++
+		p.oldLeft--
+	case '+':
+		if p.newLeft == 0 {
+			return p.hunkError(line)
+		}
+		p.hunk.Lines = append(p.hunk.Lines, Line{Type: LineAdded, Content: line[1:], NewNumber: p.newLine})
+		p.newLine++
+		p.newLeft--
+	case '\\':
+		p.markNoNewline()
+	default:
+		return p.hunkError(line)
+	}
+	return nil
+}
+
+func (p *diffParser) hunkError(line string) error {
+	return fmt.Errorf("unexpected line in hunk of %s: %q", p.file.Path(), line)
+}
+
+func (p *diffParser) markNoNewline() {
+	if p.hunk != nil && len(p.hunk.Lines) > 0 {
+		p.hunk.Lines[len(p.hunk.Lines)-1].NoNewlineAtEOF = true
+	}
+}
+
+// splitHeaderPaths splits the "a/<old> b/<new>" part of a diff --git line.
+// Unquoted paths containing spaces are ambiguous, they are split in two equal
+// halves when possible, renames are fixed later by the extended headers.
+func splitHeaderPaths(s string) (string, string) {
+	if quoted, err := strconv.QuotedPrefix(s); err == nil {
+		return unquotePath(quoted), unquotePath(strings.TrimPrefix(s[len(quoted):], " "))
+	}
+	if i := strings.Index(s, ` "`); i >= 0 {
+		return s[:i], unquotePath(s[i+1:])
+	}
+	if half := len(s) / 2; len(s)%2 == 1 && s[half] == ' ' && trimPathPrefix(s[:half]) == trimPathPrefix(s[half+1:]) {
+		return s[:half], s[half+1:]
+	}
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, s
+}
+
+// parseMarkerPath parses the path of a ---/+++ line, git appends a tab to
+// paths containing spaces.
+func parseMarkerPath(s string) string {
+	s = strings.TrimSuffix(s, "\t")
+	if s == "/dev/null" {
+		return ""
+	}
+	return trimPathPrefix(unquotePath(s))
+}
+
+// unquotePath decodes the C-style quoting git uses for unusual paths.
+func unquotePath(s string) string {
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+func trimPathPrefix(path string) string {
+	for _, prefix := range []string{"a/", "b/"} {
+		if strings.HasPrefix(path, prefix) {
+			return path[len(prefix):]
+		}
+	}
+	return path
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,418 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		expected []FileDiff
+	}{
+		{
+			name:     "empty output",
+			out:      "",
+			expected: nil,
+		},
+		{
+			name: "modified file with no newline at end of file",
+			out: "diff --git a/mod.txt b/mod.txt\n" +
+				"index b2f931a..589dc1b 100644\n" +
+				"--- a/mod.txt\n" +
+				"+++ b/mod.txt\n" +
+				"@@ -1,5 +1,6 @@\n" +
+				" one\n" +
+				"-two\n" +
+				"+2\n" +
+				" three\n" +
+				" four\n" +
+				" five\n" +
+				"+six\n" +
+				"\\ No newline at end of file\n",
+			expected: []FileDiff{
+				{
+					OldPath: "mod.txt",
+					NewPath: "mod.txt",
+					Status:  FileStatusModified,
+					OldMode: "100644",
+					NewMode: "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 1, OldLines: 5, NewStart: 1, NewLines: 6,
+							Lines: []Line{
+								{Type: LineContext, Content: "one", OldNumber: 1, NewNumber: 1},
+								{Type: LineDeleted, Content: "two", OldNumber: 2},
+								{Type: LineAdded, Content: "2", NewNumber: 2},
+								{Type: LineContext, Content: "three", OldNumber: 3, NewNumber: 3},
+								{Type: LineContext, Content: "four", OldNumber: 4, NewNumber: 4},
+								{Type: LineContext, Content: "five", OldNumber: 5, NewNumber: 5},
+								{Type: LineAdded, Content: "six", NewNumber: 6, NoNewlineAtEOF: true},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "git show with zero context hunks",
+			out: "commit 085c5fd87f6417f0e7e1b4802b6a9493e874deab\n" +
+				"Author: a <a@b>\n" +
+				"Date:   Sat Oct 17 00:39:22 2026 +0000\n" +
+				"\n" +
+				"    second\n" +
+				"\n" +
+				"diff --git a/mod.txt b/mod.txt\n" +
+				"index b2f931a..589dc1b 100644\n" +
+				"--- a/mod.txt\n" +
+				"+++ b/mod.txt\n" +
+				"@@ -2 +2 @@ one\n" +
+				"-two\n" +
+				"+2\n" +
+				"@@ -5,0 +6 @@ five\n" +
+				"+six\n" +
+				"\\ No newline at end of file\n",
+			expected: []FileDiff{
+				{
+					OldPath: "mod.txt",
+					NewPath: "mod.txt",
+					Status:  FileStatusModified,
+					OldMode: "100644",
+					NewMode: "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Section: "one",
+							Lines: []Line{
+								{Type: LineDeleted, Content: "two", OldNumber: 2},
+								{Type: LineAdded, Content: "2", NewNumber: 2},
+							},
+						},
+						{
+							OldStart: 5, OldLines: 0, NewStart: 6, NewLines: 1, Section: "five",
+							Lines: []Line{
+								{Type: LineAdded, Content: "six", NewNumber: 6, NoNewlineAtEOF: true},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "added and deleted files",
+			out: "diff --git a/del.txt b/del.txt\n" +
+				"deleted file mode 100644\n" +
+				"index b023018..0000000\n" +
+				"--- a/del.txt\n" +
+				"+++ /dev/null\n" +
+				"@@ -1 +0,0 @@\n" +
+				"-bye\n" +
+				"diff --git a/empty.txt b/empty.txt\n" +
+				"new file mode 100644\n" +
+				"index 0000000..e69de29\n",
+			expected: []FileDiff{
+				{
+					OldPath: "del.txt",
+					Status:  FileStatusDeleted,
+					OldMode: "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0,
+							Lines: []Line{
+								{Type: LineDeleted, Content: "bye", OldNumber: 1},
+							},
+						},
+					},
+				},
+				{
+					NewPath: "empty.txt",
+					Status:  FileStatusAdded,
+					NewMode: "100644",
+				},
+			},
+		},
+		{
+			name: "renamed and copied files",
+			out: "diff --git a/copysrc.txt b/copy.txt\n" +
+				"similarity index 85%\n" +
+				"copy from copysrc.txt\n" +
+				"copy to copy.txt\n" +
+				"index 0fdf397..f9d9a01 100644\n" +
+				"--- a/copysrc.txt\n" +
+				"+++ b/copy.txt\n" +
+				"@@ -4,3 +4,4 @@ c\n" +
+				" d\n" +
+				" e\n" +
+				" f\n" +
+				"+g\n" +
+				"diff --git a/copy.txt b/renamed.txt\n" +
+				"similarity index 100%\n" +
+				"rename from copy.txt\n" +
+				"rename to renamed.txt\n",
+			expected: []FileDiff{
+				{
+					OldPath:    "copysrc.txt",
+					NewPath:    "copy.txt",
+					Status:     FileStatusCopied,
+					Similarity: 85,
+					OldMode:    "100644",
+					NewMode:    "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 4, OldLines: 3, NewStart: 4, NewLines: 4, Section: "c",
+							Lines: []Line{
+								{Type: LineContext, Content: "d", OldNumber: 4, NewNumber: 4},
+								{Type: LineContext, Content: "e", OldNumber: 5, NewNumber: 5},
+								{Type: LineContext, Content: "f", OldNumber: 6, NewNumber: 6},
+								{Type: LineAdded, Content: "g", NewNumber: 7},
+							},
+						},
+					},
+				},
+				{
+					OldPath:    "copy.txt",
+					NewPath:    "renamed.txt",
+					Status:     FileStatusRenamed,
+					Similarity: 100,
+				},
+			},
+		},
+		{
+			name: "binary and mode change",
+			out: "diff --git a/bin.dat b/bin.dat\n" +
+				"index 8352675..a903574 100644\n" +
+				"Binary files a/bin.dat and b/bin.dat differ\n" +
+				"diff --git a/run.sh b/run.sh\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n",
+			expected: []FileDiff{
+				{
+					OldPath: "bin.dat",
+					NewPath: "bin.dat",
+					Status:  FileStatusModified,
+					OldMode: "100644",
+					NewMode: "100644",
+					Binary:  true,
+				},
+				{
+					OldPath: "run.sh",
+					NewPath: "run.sh",
+					Status:  FileStatusModified,
+					OldMode: "100644",
+					NewMode: "100755",
+				},
+			},
+		},
+		{
+			name: "paths with spaces and quoted paths",
+			out: "diff --git a/with space.txt b/with space.txt\n" +
+				"index f05367e..289e8b9 100644\n" +
+				"--- a/with space.txt\t\n" +
+				"+++ b/with space.txt\t\n" +
+				"@@ -1 +1,2 @@\n" +
+				" sp\n" +
+				"+more\n" +
+				"diff --git \"a/h\\303\\251llo.txt\" \"b/h\\303\\251llo.txt\"\n" +
+				"new file mode 100644\n" +
+				"index 0000000..45a6154\n" +
+				"--- /dev/null\n" +
+				"+++ \"b/h\\303\\251llo.txt\"\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+hé\n",
+			expected: []FileDiff{
+				{
+					OldPath: "with space.txt",
+					NewPath: "with space.txt",
+					Status:  FileStatusModified,
+					OldMode: "100644",
+					NewMode: "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 2,
+							Lines: []Line{
+								{Type: LineContext, Content: "sp", OldNumber: 1, NewNumber: 1},
+								{Type: LineAdded, Content: "more", NewNumber: 2},
+							},
+						},
+					},
+				},
+				{
+					NewPath: "héllo.txt",
+					Status:  FileStatusAdded,
+					NewMode: "100644",
+					Hunks: []Hunk{
+						{
+							OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1,
+							Lines: []Line{
+								{Type: LineAdded, Content: "hé", NewNumber: 1},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "renamed path with spaces without content change",
+			out: "diff --git a/old name.txt b/new name.txt\n" +
+				"similarity index 100%\n" +
+				"rename from old name.txt\n" +
+				"rename to new name.txt\n",
+			expected: []FileDiff{
+				{
+					OldPath:    "old name.txt",
+					NewPath:    "new name.txt",
+					Status:     FileStatusRenamed,
+					Similarity: 100,
+				},
+			},
+		},
+		{
+			name: "combined diff of a merge commit",
+			out: "diff --cc conflict.txt\n" +
+				"index 3a2e3f4,7b8a9c1..0d1e2f3\n" +
+				"--- a/conflict.txt\n" +
+				"+++ b/conflict.txt\n" +
+				"@@@ -1,1 -1,1 +1,1 @@@\n" +
+				"- ours\n" +
+				" -theirs\n" +
+				"++merged\n",
+			expected: []FileDiff{
+				{
+					OldPath:  "conflict.txt",
+					NewPath:  "conflict.txt",
+					Status:   FileStatusModified,
+					Combined: true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParseDiff([]byte(tt.out))
+
+			require.NoError(t, err)
+			for i := range files {
+				files[i].Raw = ""
+			}
+			assert.Equal(t, tt.expected, files)
+		})
+	}
+}
+
+func TestParseDiff_Raw(t *testing.T) {
+	first := "diff --git a/a.txt b/a.txt\n" +
+		"index 8352675..a903574 100644\n" +
+		"--- a/a.txt\n" +
+		"+++ b/a.txt\n" +
+		"@@ -1 +1 @@\n" +
+		"-a\n" +
+		"+b\n"
+	second := "diff --git a/run.sh b/run.sh\n" +
+		"old mode 100644\n" +
+		"new mode 100755\n"
+
+	files, err := ParseDiff([]byte("commit 085c5fd\n\n    message\n\n" + first + second))
+
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, first, files[0].Raw)
+	assert.Equal(t, second, files[1].Raw)
+}
+
+func TestParseDiff_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		out         string
+		expectedErr string
+	}{
+		{
+			name: "invalid hunk header",
+			out: "diff --git a/a.txt b/a.txt\n" +
+				"@@ -a +b @@\n",
+			expectedErr: `invalid hunk header in a.txt: "@@ -a +b @@"`,
+		},
+		{
+			name: "truncated hunk",
+			out: "diff --git a/a.txt b/a.txt\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" a\n",
+			expectedErr: "truncated hunk in a.txt",
+		},
+		{
+			name: "next file inside a hunk",
+			out: "diff --git a/a.txt b/a.txt\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" a\n" +
+				"diff --git a/b.txt b/b.txt\n",
+			expectedErr: `unexpected line in hunk of a.txt: "diff --git a/b.txt b/b.txt"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParseDiff([]byte(tt.out))
+
+			assert.Nil(t, files)
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestDiffResult_Files(t *testing.T) {
+	result := DiffResult{Out: []byte(strings.Join([]string{
+		"diff --git a/del.txt b/del.txt",
+		"deleted file mode 100644",
+		"index b023018..0000000",
+		"--- a/del.txt",
+		"+++ /dev/null",
+		"@@ -1,2 +0,0 @@",
+		"-bye",
+		"-now",
+		"",
+	}, "\n"))}
+
+	files, err := result.Files()
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "del.txt", files[0].Path())
+	assert.Equal(t, 0, files[0].Additions())
+	assert.Equal(t, 2, files[0].Deletions())
+}
+
+func TestFileDiff_Regular(t *testing.T) {
+	files, err := ParseDiff([]byte(strings.Join([]string{
+		"diff --git a/lib b/lib",
+		"index 8352675..a903574 160000",
+		"--- a/lib",
+		"+++ b/lib",
+		"@@ -1 +1 @@",
+		"-Subproject commit 8352675",
+		"+Subproject commit a903574",
+		"diff --git a/link b/link",
+		"index 8352675..a903574 120000",
+		"--- a/link",
+		"+++ b/link",
+		"@@ -1 +1 @@",
+		"-dir",
+		"+other",
+		"diff --git a/a.txt b/a.txt",
+		"index 8352675..a903574 100644",
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1 +1 @@",
+		"-a",
+		"+b",
+		"",
+	}, "\n")))
+
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.False(t, files[0].Regular())
+	assert.False(t, files[1].Regular())
+	assert.True(t, files[2].Regular())
+}
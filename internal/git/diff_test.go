@@ -2,10 +2,14 @@ package git
 
 import (
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockCommand struct {
@@ -86,11 +90,17 @@ func TestBuildGenericArgs(t *testing.T) {
 			opts:   DiffOptions{Unified: 2, Filters: []string{"a.txt", "b.txt"}},
 			expect: []string{"show", "8062f1", "--unified=2", "--", "a.txt", "b.txt"},
 		},
+		{
+			name:   "Diff staged with function context",
+			base:   []string{"diff", "--cached"},
+			opts:   DiffOptions{Unified: 0, FunctionContext: true},
+			expect: []string{"diff", "--cached", "--unified=0", "--function-context"},
+		},
 		{
 			name:   "All together",
 			base:   []string{"diff"},
-			opts:   DiffOptions{Unified: 7, FindRenames: true, Filters: []string{"f.go"}},
-			expect: []string{"diff", "--unified=7", "--find-renames", "--", "f.go"},
+			opts:   DiffOptions{Unified: 7, FindRenames: true, FunctionContext: true, Filters: []string{"f.go"}},
+			expect: []string{"diff", "--unified=7", "--find-renames", "--function-context", "--", "f.go"},
 		},
 	}
 	for _, tt := range tests {
@@ -124,6 +134,47 @@ func TestDiffRefs(t *testing.T) {
 	mockCmd.AssertExpectations(t)
 }
 
+func TestDiffRefs_MergeBase(t *testing.T) {
+	var args []string
+	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith: ".",
+		onExecCommander: func(name string, cmdArgs ...string) {
+			args = cmdArgs
+		},
+	})
+	defer resetExecCommander()
+
+	_, err := DiffRefs("main", "dev", DiffOptions{CliPath: "git", CliWd: ".", Unified: 3, MergeBase: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"diff", "main...dev", "--unified=3"}, args)
+
+	mockCmd.AssertExpectations(t)
+}
+
+func TestDiffRefs_MergeBaseRepo(t *testing.T) {
+	dir := newTestRepo(t)
+	git := func(args ...string) {
+		require.NoError(t, exec.Command("git", append([]string{"-C", dir}, args...)...).Run())
+	}
+	git("branch", "-M", "main")
+	git("checkout", "--quiet", "-b", "dev")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "feature.go"), []byte("package feature\n"), 0o600))
+	git("add", ".")
+	git("commit", "--quiet", "-m", "feature")
+	git("checkout", "--quiet", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hotfix.go"), []byte("package hotfix\n"), 0o600))
+	git("add", ".")
+	git("commit", "--quiet", "-m", "hotfix")
+
+	res, err := DiffRefs("main", "dev", DiffOptions{CliPath: "git", CliWd: dir, Unified: 3, MergeBase: true})
+	require.NoError(t, err)
+
+	files, err := res.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "feature.go", files[0].Path())
+}
+
 func TestDiffStaged(t *testing.T) {
 	var execCommanderCalledWith map[string]any
 	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
@@ -147,6 +198,114 @@ func TestDiffStaged(t *testing.T) {
 	mockCmd.AssertExpectations(t)
 }
 
+func TestDiffWorktree(t *testing.T) {
+	var args []string
+	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith: ".",
+		onExecCommander: func(name string, cmdArgs ...string) {
+			args = cmdArgs
+		},
+	})
+	defer resetExecCommander()
+
+	_, err := DiffWorktree(DiffOptions{CliPath: "git", CliWd: ".", Unified: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"diff", "--unified=3"}, args)
+
+	mockCmd.AssertExpectations(t)
+}
+
+func TestDiffAll(t *testing.T) {
+	var args []string
+	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith: ".",
+		onExecCommander: func(name string, cmdArgs ...string) {
+			args = cmdArgs
+		},
+	})
+	defer resetExecCommander()
+
+	_, err := DiffAll(DiffOptions{CliPath: "git", CliWd: ".", Unified: 3, Filters: []string{"*.go"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"diff", "HEAD", "--unified=3", "--", "*.go"}, args)
+
+	mockCmd.AssertExpectations(t)
+}
+
+// newTestRepo creates a git repository with a committed file, skipping the
+// test when git isn't installed.
+func newTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+	} {
+		require.NoError(t, exec.Command("git", append([]string{"-C", dir}, args...)...).Run())
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.go"), []byte("package main\n"), 0o600))
+	require.NoError(t, exec.Command("git", "-C", dir, "add", ".").Run())
+	require.NoError(t, exec.Command("git", "-C", dir, "commit", "--quiet", "-m", "init").Run())
+	return dir
+}
+
+func TestDiffWorktree_Untracked(t *testing.T) {
+	dir := newTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.go"), []byte("package main\n\nfunc main() {}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package new\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("todo\n"), 0o600))
+
+	res, err := DiffWorktree(DiffOptions{CliPath: "git", CliWd: dir, Unified: 3, Filters: []string{"*.go"}, Untracked: true})
+	require.NoError(t, err)
+	assert.Equal(t, "git diff --unified=3 -- *.go + 1 untracked files", res.FullCommand)
+
+	files, err := res.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "tracked.go", files[0].Path())
+	assert.Equal(t, FileStatusModified, files[0].Status)
+	assert.Equal(t, "new.go", files[1].Path())
+	assert.Equal(t, FileStatusAdded, files[1].Status)
+	assert.Equal(t, 1, files[1].Additions())
+}
+
+func TestDiffWorktree_UntrackedFromSubdirectory(t *testing.T) {
+	dir := newTestRepo(t)
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.go"), []byte("package main\n\nfunc main() {}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "u.txt"), []byte("todo\n"), 0o600))
+
+	res, err := DiffWorktree(DiffOptions{CliPath: "git", CliWd: sub, Unified: 3, Untracked: true})
+	require.NoError(t, err)
+
+	files, err := res.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "tracked.go", files[0].Path())
+	assert.Equal(t, "sub/u.txt", files[1].Path())
+	assert.Equal(t, FileStatusAdded, files[1].Status)
+}
+
+func TestDiffAll_StagedAndUnstaged(t *testing.T) {
+	dir := newTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "staged.go"), []byte("package staged\n"), 0o600))
+	require.NoError(t, exec.Command("git", "-C", dir, "add", "staged.go").Run())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.go"), []byte("package changed\n"), 0o600))
+
+	res, err := DiffAll(DiffOptions{CliPath: "git", CliWd: dir, Unified: 3})
+	require.NoError(t, err)
+
+	files, err := res.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "staged.go", files[0].Path())
+	assert.Equal(t, "tracked.go", files[1].Path())
+}
+
 func TestDiffCommit(t *testing.T) {
 	var execCommanderCalledWith map[string]any
 	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// INDEX and WORKTREE are the refs of ReadFile reading the staged version of
+// a file and the one of the working tree, no commit being named like them.
+const (
+	INDEX    = ":index"
+	WORKTREE = ":worktree"
+)
+
+// ErrNotRegularFile is returned by ReadFile for a path of the working tree
+// which isn't a regular file, like a submodule or a link to a directory.
+var ErrNotRegularFile = errors.New("not a regular file")
+
+// ReadFile returns the content of path, relative to the repository root like
+// the paths of a diff, in the commit ref, the index or the working tree.
+func ReadFile(ref string, path string, diffOptions DiffOptions) ([]byte, error) {
+	if ref == WORKTREE {
+		root, err := topLevel(diffOptions)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(root, path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotRegularFile)
+		}
+		return os.ReadFile(path)
+	}
+
+	if ref == INDEX {
+		ref = ""
+	}
+	res, err := runCli(diffOptions.CliPath, diffOptions.CliWd, "show", ref+":"+path)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(res.Out)))
+	}
+	return res.Out, nil
+}
+
+// topLevel returns the root of the repository holding the working directory.
+func topLevel(diffOptions DiffOptions) (string, error) {
+	res, err := runCli(diffOptions.CliPath, diffOptions.CliWd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(res.Out)))
+	}
+	return strings.TrimSpace(string(res.Out)), nil
+}
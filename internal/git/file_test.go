@@ -0,0 +1,66 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	var args []string
+	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith:  ".",
+		combinedOutputOut: []byte("package main\n"),
+		onExecCommander: func(name string, cmdArgs ...string) {
+			args = cmdArgs
+		},
+	})
+	defer resetExecCommander()
+
+	content, err := ReadFile("abc", "cmd/main.go", DiffOptions{CliPath: "git", CliWd: "."})
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+	assert.Equal(t, []string{"show", "abc:cmd/main.go"}, args)
+
+	_, err = ReadFile(INDEX, "cmd/main.go", DiffOptions{CliPath: "git", CliWd: "."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"show", ":cmd/main.go"}, args)
+
+	mockCmd.AssertExpectations(t)
+}
+
+func TestReadFile_Repo(t *testing.T) {
+	dir := newTestRepo(t)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.go"), []byte("package staged\n"), 0o600))
+	require.NoError(t, exec.Command("git", "-C", dir, "add", ".").Run())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.go"), []byte("package worktree\n"), 0o600))
+	options := DiffOptions{CliPath: "git", CliWd: filepath.Join(dir, "sub")}
+
+	tests := []struct {
+		ref    string
+		path   string
+		expect string
+	}{
+		{"HEAD", "tracked.go", "package main\n"},
+		{INDEX, "sub/a.go", "package staged\n"},
+		{WORKTREE, "sub/a.go", "package worktree\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			content, err := ReadFile(tt.ref, tt.path, options)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, string(content))
+		})
+	}
+
+	_, err := ReadFile("HEAD", "sub/a.go", options)
+	assert.ErrorContains(t, err, "exit status 128")
+
+	_, err = ReadFile(WORKTREE, "sub", options)
+	assert.ErrorIs(t, err, ErrNotRegularFile)
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logFormat separates the fields of a commit with the unit separator and
+// commits with the record separator, which commit messages don't contain.
+const logFormat = "--format=%H%x1f%an <%ae>%x1f%aI%x1f%s%x1f%b%x1f%(trailers:only,unfold)%x1e"
+
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// Commit is the metadata of a commit, Body is the message without its
+// subject and trailers.
+type Commit struct {
+	Hash     string
+	Author   string
+	Date     string
+	Subject  string
+	Body     string
+	Trailers []Trailer
+}
+
+// LogCommit returns the metadata of the commit ref.
+func LogCommit(ref string, diffOptions DiffOptions) ([]Commit, error) {
+	return runLog(diffOptions, "log", "-1", logFormat, ref)
+}
+
+// LogRefs returns the metadata of the commits of refTo missing from refFrom,
+// oldest first, limited to the commits touching the filtered paths.
+func LogRefs(refFrom string, refTo string, diffOptions DiffOptions) ([]Commit, error) {
+	args := []string{"log", "--reverse", logFormat, refFrom + ".." + refTo}
+	if len(diffOptions.Filters) > 0 {
+		args = append(append(args, "--"), diffOptions.Filters...)
+	}
+	return runLog(diffOptions, args...)
+}
+
+func runLog(diffOptions DiffOptions, args ...string) ([]Commit, error) {
+	res, err := runCli(diffOptions.CliPath, diffOptions.CliWd, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(res.Out)))
+	}
+	return parseLog(string(res.Out))
+}
+
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimPrefix(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.Split(record, "\x1f")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid git log record %q", record)
+		}
+
+		commit := Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Subject: fields[3],
+		}
+		trailers := strings.TrimSpace(fields[5])
+		for _, line := range strings.Split(trailers, "\n") {
+			if key, value, found := strings.Cut(line, ":"); found {
+				commit.Trailers = append(commit.Trailers, Trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+			}
+		}
+		// %b ends with the trailers, already listed apart
+		commit.Body = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fields[4]), trailers))
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
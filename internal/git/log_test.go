@@ -0,0 +1,80 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRefs(t *testing.T) {
+	var args []string
+	mockCmd, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith:  ".",
+		combinedOutputOut: []byte("abc\x1fJane <jane@example.com>\x1f2025-01-02T03:04:05+00:00\x1fFix parser\x1fHandle empty input.\n\nFixes: #12\n\x1fFixes: #12\n\x1e\n"),
+		onExecCommander: func(name string, cmdArgs ...string) {
+			args = cmdArgs
+		},
+	})
+	defer resetExecCommander()
+
+	commits, err := LogRefs("main", "dev", DiffOptions{CliPath: "git", CliWd: ".", Filters: []string{"*.go"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log", "--reverse", logFormat, "main..dev", "--", "*.go"}, args)
+	assert.Equal(t, []Commit{{
+		Hash:     "abc",
+		Author:   "Jane <jane@example.com>",
+		Date:     "2025-01-02T03:04:05+00:00",
+		Subject:  "Fix parser",
+		Body:     "Handle empty input.",
+		Trailers: []Trailer{{Key: "Fixes", Value: "#12"}},
+	}}, commits)
+
+	mockCmd.AssertExpectations(t)
+}
+
+func TestLogCommit_InvalidOutput(t *testing.T) {
+	_, resetExecCommander := newMockedExecCommander(mockedExecCommanderOptions{
+		setDirCalledWith:  ".",
+		combinedOutputOut: []byte("fatal: bad revision\n"),
+	})
+	defer resetExecCommander()
+
+	_, err := LogCommit("abc", DiffOptions{CliPath: "git", CliWd: "."})
+
+	assert.EqualError(t, err, `invalid git log record "fatal: bad revision\n"`)
+}
+
+func TestLogRefs_Repo(t *testing.T) {
+	dir := newTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o600))
+	require.NoError(t, exec.Command("git", "-C", dir, "add", ".").Run())
+	require.NoError(t, exec.Command("git", "-C", dir, "commit", "--quiet", "-m", "Add a", "-m", "First line.\nSecond line.", "-m", "Refs: JIRA-1\nSigned-off-by: test <test@example.com>").Run())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"), 0o600))
+	require.NoError(t, exec.Command("git", "-C", dir, "add", ".").Run())
+	require.NoError(t, exec.Command("git", "-C", dir, "commit", "--quiet", "-m", "Add b").Run())
+
+	commits, err := LogRefs("HEAD~2", "HEAD", DiffOptions{CliPath: "git", CliWd: dir})
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Equal(t, "Add a", commits[0].Subject)
+	assert.Equal(t, "First line.\nSecond line.", commits[0].Body)
+	assert.Equal(t, []Trailer{{Key: "Refs", Value: "JIRA-1"}, {Key: "Signed-off-by", Value: "test <test@example.com>"}}, commits[0].Trailers)
+	assert.Equal(t, "test <test@example.com>", commits[0].Author)
+	assert.Equal(t, "Add b", commits[1].Subject)
+	assert.Empty(t, commits[1].Body)
+	assert.Empty(t, commits[1].Trailers)
+
+	commits, err = LogCommit("HEAD", DiffOptions{CliPath: "git", CliWd: dir})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "Add b", commits[0].Subject)
+
+	commits, err = LogRefs("HEAD~2", "HEAD", DiffOptions{CliPath: "git", CliWd: dir, Filters: []string{"a.go"}})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "Add a", commits[0].Subject)
+}
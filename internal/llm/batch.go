@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// LLMBatchResult is the outcome of one request of SendBatch, Err only
+// concerns this request.
+type LLMBatchResult struct {
+	Response *LLMSendResponse
+	Err      error
+}
+
+type LLMBatchOptions struct {
+	// Concurrency is the number of requests in flight, 1 when not positive.
+	Concurrency int
+	// OnDone is called once per request as soon as it is over, never by two
+	// workers at the same time.
+	OnDone func(index int, result LLMBatchResult)
+}
+
+// SendBatch sends every conversation with a bounded pool of workers and
+// returns the results in the order of requests. A failed request doesn't stop
+// the others, the ones not started yet when ctx is cancelled fail with its
+// error.
+func SendBatch(ctx context.Context, client LLMClient, requests [][]Message, opts LLMBatchOptions) []LLMBatchResult {
+	results := make([]LLMBatchResult, len(requests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var onDone sync.Mutex
+
+	for range min(max(opts.Concurrency, 1), len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := LLMBatchResult{Err: ctx.Err()}
+				if result.Err == nil {
+					result.Response, result.Err = client.Send(ctx, requests[i])
+				}
+				results[i] = result
+
+				if opts.OnDone != nil {
+					onDone.Lock()
+					opts.OnDone(i, result)
+					onDone.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
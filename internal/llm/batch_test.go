@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchTestClient struct {
+	mu          sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+	send        func(ctx context.Context, messages []Message) (*LLMSendResponse, error)
+}
+
+func (c *batchTestClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+	return c.send(ctx, messages)
+}
+
+func (c *batchTestClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	panic("not used by SendBatch")
+}
+
+func batchRequests(contents ...string) [][]Message {
+	requests := make([][]Message, len(contents))
+	for i, content := range contents {
+		requests[i] = []Message{{Role: User, Content: content}}
+	}
+	return requests
+}
+
+func echoAfter(delay time.Duration) func(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	return func(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+		time.Sleep(delay)
+		return &LLMSendResponse{Content: messages[0].Content}, nil
+	}
+}
+
+func TestSendBatch_BoundedAndOrdered(t *testing.T) {
+	client := &batchTestClient{send: echoAfter(10 * time.Millisecond)}
+	var done []int
+
+	results := SendBatch(t.Context(), client, batchRequests("a", "b", "c", "d", "e", "f"), LLMBatchOptions{
+		Concurrency: 2,
+		OnDone: func(index int, result LLMBatchResult) {
+			done = append(done, index)
+		},
+	})
+
+	require.Len(t, results, 6)
+	for i, content := range []string{"a", "b", "c", "d", "e", "f"} {
+		assert.NoError(t, results[i].Err)
+		assert.Equal(t, content, results[i].Response.Content)
+	}
+	assert.Equal(t, 2, client.maxInFlight)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5}, done)
+}
+
+func TestSendBatch_DefaultsToOneWorker(t *testing.T) {
+	client := &batchTestClient{send: echoAfter(time.Millisecond)}
+
+	results := SendBatch(t.Context(), client, batchRequests("a", "b", "c"), LLMBatchOptions{})
+
+	assert.Len(t, results, 3)
+	assert.Equal(t, 1, client.maxInFlight)
+}
+
+func TestSendBatch_ErrorIsolation(t *testing.T) {
+	client := &batchTestClient{send: func(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+		if messages[0].Content == "b" {
+			return nil, errors.New("rate limited")
+		}
+		return &LLMSendResponse{Content: messages[0].Content}, nil
+	}}
+
+	results := SendBatch(t.Context(), client, batchRequests("a", "b", "c"), LLMBatchOptions{Concurrency: 3})
+
+	assert.Equal(t, []LLMBatchResult{
+		{Response: &LLMSendResponse{Content: "a"}},
+		{Err: errors.New("rate limited")},
+		{Response: &LLMSendResponse{Content: "c"}},
+	}, results)
+}
+
+func TestSendBatch_Cancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	client := &batchTestClient{send: func(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+		cancel()
+		return nil, ctx.Err()
+	}}
+
+	results := SendBatch(ctx, client, batchRequests("a", "b", "c"), LLMBatchOptions{Concurrency: 1})
+
+	assert.Equal(t, 1, client.calls, "requests after the cancellation should not be sent")
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	}
+}
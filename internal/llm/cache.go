@@ -0,0 +1,252 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const CACHE_ENTRY_EXT = ".json"
+
+// ResponseCache stores responses on disk, one file per request key. Entries
+// older than ttl are ignored and overwritten.
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	CreatedAt  time.Time `json:"created_at"`
+	Content    string    `json:"content"`
+	AnsweredBy string    `json:"answered_by,omitempty"`
+}
+
+type CacheStats struct {
+	Entries int
+	Expired int
+	Size    int64
+}
+
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// CacheKey identifies a request to the model of opts, only the role and
+// content of messages change what the model answers. The endpoint, headers,
+// organization, project and API version are part of the key, two servers or
+// gateway routes may serve different models under the same name. The key is
+// a hash, the header values it covers are never stored.
+func CacheKey(provider LLMProvider, opts LLMClientOptions, messages []Message) string {
+	type keyMessage struct {
+		Role    MessageRole
+		Content string
+	}
+	key := struct {
+		Provider   LLMProvider
+		Model      string
+		OpenAI     OpenAIOptions
+		Azure      AzureOpenAIOptions
+		Generation GenerationOptions
+		Messages   []keyMessage
+	}{
+		Provider:   provider,
+		Model:      opts.Model,
+		OpenAI:     opts.OpenAI,
+		Azure:      opts.Azure,
+		Generation: opts.Generation,
+	}
+	for _, message := range messages {
+		key.Messages = append(key.Messages, keyMessage{Role: message.Role, Content: message.Content})
+	}
+
+	payload, _ := json.Marshal(key)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the response stored for key, unless missing, unreadable or
+// expired. It is marked as Cached and has no usage, since answering it spent
+// no tokens.
+func (c *ResponseCache) Get(key string) (*LLMSendResponse, bool) {
+	raw, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || c.expired(entry) {
+		return nil, false
+	}
+	return &LLMSendResponse{
+		Content:    entry.Content,
+		AnsweredBy: entry.AnsweredBy,
+		Cached:     true,
+	}, true
+}
+
+// Put stores res for key, through a rename so concurrent readers never see
+// a partial entry.
+func (c *ResponseCache) Put(key string, res *LLMSendResponse) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(cacheEntry{
+		CreatedAt:  c.now(),
+		Content:    res.Content,
+		AnsweredBy: res.AnsweredBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Clear removes every entry and returns how many there were.
+func (c *ResponseCache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range files {
+		if err := os.Remove(file); err != nil {
+			return i, err
+		}
+	}
+	return len(files), nil
+}
+
+func (c *ResponseCache) Stats() (CacheStats, error) {
+	var stats CacheStats
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return stats, err
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+
+		var entry cacheEntry
+		raw, err := os.ReadFile(file)
+		if err != nil || json.Unmarshal(raw, &entry) != nil || c.expired(entry) {
+			stats.Expired++
+		}
+	}
+	return stats, nil
+}
+
+func (c *ResponseCache) Dir() string {
+	return c.dir
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key+CACHE_ENTRY_EXT)
+}
+
+func (c *ResponseCache) expired(entry cacheEntry) bool {
+	return c.ttl > 0 && c.now().Sub(entry.CreatedAt) > c.ttl
+}
+
+func (c *ResponseCache) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() && strings.HasSuffix(dirEntry.Name(), CACHE_ENTRY_EXT) {
+			files = append(files, filepath.Join(c.dir, dirEntry.Name()))
+		}
+	}
+	return files, nil
+}
+
+type cachingClient struct {
+	client  LLMClient
+	cache   *ResponseCache
+	key     func(messages []Message) string
+	primary string
+}
+
+// NewCachingClient answers the requests already sent from cache, key
+// identifying a request, see CacheKey. Failed or interrupted answers are not
+// stored, nor the answers of a fallback target since the key is the one of
+// primary, the provider:model of the first target.
+func NewCachingClient(client LLMClient, cache *ResponseCache, key func(messages []Message) string, primary string) LLMClient {
+	return &cachingClient{client: client, cache: cache, key: key, primary: primary}
+}
+
+// put stores res unless it was answered by a fallback target, see
+// LLMSendResponse.AnsweredBy.
+func (c *cachingClient) put(key string, res *LLMSendResponse) {
+	if res.AnsweredBy != "" && res.AnsweredBy != c.primary {
+		return
+	}
+	// a cache that can't be written only costs the next run
+	_ = c.cache.Put(key, res)
+}
+
+func (c *cachingClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	key := c.key(messages)
+	if res, ok := c.cache.Get(key); ok {
+		return res, nil
+	}
+	res, err := c.client.Send(ctx, messages)
+	if err != nil {
+		return res, err
+	}
+	c.put(key, res)
+	return res, nil
+}
+
+// Stream replays a cached answer as a single content event.
+func (c *cachingClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	key := c.key(messages)
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		if res, ok := c.cache.Get(key); ok {
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: res.Content, AnsweredBy: res.AnsweredBy, Cached: true}
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: res.Content, AnsweredBy: res.AnsweredBy, Cached: true}
+			return
+		}
+
+		var content strings.Builder
+		for event := range c.client.Stream(ctx, messages) {
+			switch event.Type {
+			case LLMStreamEventTypeMessage:
+				content.WriteString(event.Content)
+			case LLMStreamEventTypeComplete:
+				c.put(key, &LLMSendResponse{Content: content.String(), AnsweredBy: event.AnsweredBy})
+			}
+			out <- event
+		}
+	}()
+	return out
+}
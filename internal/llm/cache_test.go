@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResponseCache(t *testing.T, ttl time.Duration) (*ResponseCache, *time.Time) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewResponseCache(filepath.Join(t.TempDir(), "responses"), ttl)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestCacheKey(t *testing.T) {
+	temperature := 0.2
+	messages := []Message{{Role: System, Content: "prompt"}, {Role: User, Content: "diff"}}
+	opts := LLMClientOptions{Model: "gpt-4.1"}
+	key := CacheKey(LLMProviderOpenAI, opts, messages)
+
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, CacheKey(LLMProviderOpenAI, opts, []Message{
+		{Role: System, Content: "prompt", Hidden: true},
+		{Role: User, Content: "diff", Hidden: true},
+	}))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOllama, opts, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1-mini"}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", Generation: GenerationOptions{Temperature: &temperature}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", OpenAI: OpenAIOptions{BaseURL: "http://localhost:8000/v1"}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", Azure: AzureOpenAIOptions{Endpoint: "https://a.openai.azure.com"}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", Azure: AzureOpenAIOptions{Deployment: "prod"}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", Azure: AzureOpenAIOptions{APIVersion: "2024-10-21"}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", OpenAI: OpenAIOptions{Organization: "org"}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", OpenAI: OpenAIOptions{Project: "proj"}}, messages))
+	headers := CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", OpenAI: OpenAIOptions{Headers: map[string]string{"X-Route": "a"}}}, messages)
+	assert.NotEqual(t, key, headers)
+	assert.NotEqual(t, headers, CacheKey(LLMProviderOpenAI, LLMClientOptions{Model: "gpt-4.1", OpenAI: OpenAIOptions{Headers: map[string]string{"X-Route": "b"}}}, messages))
+	assert.NotEqual(t, key, CacheKey(LLMProviderOpenAI, opts, messages[:1]))
+}
+
+func TestResponseCache_PutGet(t *testing.T) {
+	cache, now := newTestResponseCache(t, time.Hour)
+
+	_, found := cache.Get("key")
+	assert.False(t, found)
+
+	require.NoError(t, cache.Put("key", &LLMSendResponse{Content: "review", Usage: LLMTokenUsage{InputTokens: 10}, AnsweredBy: "ollama:llama3"}))
+	res, found := cache.Get("key")
+	assert.True(t, found)
+	assert.Equal(t, &LLMSendResponse{Content: "review", AnsweredBy: "ollama:llama3", Cached: true}, res)
+
+	*now = now.Add(2 * time.Hour)
+	_, found = cache.Get("key")
+	assert.False(t, found)
+}
+
+func TestResponseCache_GetInvalidEntry(t *testing.T) {
+	cache, _ := newTestResponseCache(t, time.Hour)
+	require.NoError(t, os.MkdirAll(cache.Dir(), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(cache.Dir(), "key.json"), []byte("{"), 0o600))
+
+	_, found := cache.Get("key")
+
+	assert.False(t, found)
+}
+
+func TestResponseCache_StatsAndClear(t *testing.T) {
+	cache, now := newTestResponseCache(t, time.Hour)
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{}, stats)
+
+	require.NoError(t, cache.Put("old", &LLMSendResponse{Content: "old review"}))
+	*now = now.Add(2 * time.Hour)
+	require.NoError(t, cache.Put("new", &LLMSendResponse{Content: "new review"}))
+
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, 1, stats.Expired)
+	assert.Positive(t, stats.Size)
+
+	removed, err := cache.Clear()
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	_, found := cache.Get("new")
+	assert.False(t, found)
+}
+
+func TestCachingClient_Send(t *testing.T) {
+	cache, _ := newTestResponseCache(t, time.Hour)
+	inner := &retryTestClient{sends: []error{errors.New("offline"), nil}}
+	client := NewCachingClient(inner, cache, func(messages []Message) string { return messages[0].Content }, "openai:gpt-4.1")
+	messages := []Message{{Role: User, Content: "diff"}}
+
+	_, err := client.Send(t.Context(), messages)
+	assert.Error(t, err)
+
+	res, err := client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{Content: "review"}, res)
+
+	res, err = client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{Content: "review", Cached: true}, res)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCachingClient_Stream(t *testing.T) {
+	cache, _ := newTestResponseCache(t, time.Hour)
+	failed := LLMStreamEvent{Type: LLMStreamEventTypeError, Content: "offline", Err: errors.New("offline")}
+	inner := &retryTestClient{streams: [][]LLMStreamEvent{
+		{{Type: LLMStreamEventTypeMessage, Content: "hel"}, failed},
+		{
+			{Type: LLMStreamEventTypeMessage, Content: "hel"},
+			{Type: LLMStreamEventTypeMessage, Content: "lo"},
+			{Type: LLMStreamEventTypeComplete, Usage: LLMTokenUsage{OutputTokens: 2}},
+		},
+	}}
+	client := NewCachingClient(inner, cache, func(messages []Message) string { return messages[0].Content }, "openai:gpt-4.1")
+	messages := []Message{{Role: User, Content: "diff"}}
+
+	events := collectStreamEvents(client.Stream(t.Context(), messages))
+	assert.Equal(t, failed, events[len(events)-1])
+
+	events = collectStreamEvents(client.Stream(t.Context(), messages))
+	assert.Len(t, events, 3)
+
+	events = collectStreamEvents(client.Stream(t.Context(), messages))
+	assert.Equal(t, []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "hello", Cached: true},
+		{Type: LLMStreamEventTypeComplete, Content: "hello", Cached: true},
+	}, events)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func collectStreamEvents(stream <-chan LLMStreamEvent) []LLMStreamEvent {
+	var events []LLMStreamEvent
+	for event := range stream {
+		events = append(events, event)
+	}
+	return events
+}
+
+// answeringClient answers like a fallback client whose target answeredBy
+// answered.
+type answeringClient struct {
+	answeredBy string
+}
+
+func (c answeringClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	return &LLMSendResponse{Content: "review by " + c.answeredBy, AnsweredBy: c.answeredBy}, nil
+}
+
+func (c answeringClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent, 2)
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "review by " + c.answeredBy, AnsweredBy: c.answeredBy}
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, AnsweredBy: c.answeredBy}
+	close(out)
+	return out
+}
+
+func TestCachingClient_SkipsFallbackAnswers(t *testing.T) {
+	key := func(messages []Message) string { return messages[0].Content }
+	messages := []Message{{Role: User, Content: "diff"}}
+
+	tests := []struct {
+		name       string
+		answeredBy string
+		stored     bool
+	}{
+		{"fallback target", "ollama:llama3", false},
+		{"primary target", "openai:gpt-4.1", true},
+		{"without fallback", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, _ := newTestResponseCache(t, time.Hour)
+			client := NewCachingClient(answeringClient{answeredBy: tt.answeredBy}, cache, key, "openai:gpt-4.1")
+
+			_, err := client.Send(t.Context(), messages)
+			require.NoError(t, err)
+			_, sendStored := cache.Get("diff")
+			require.NoError(t, os.RemoveAll(cache.Dir()))
+			collectStreamEvents(client.Stream(t.Context(), messages))
+			_, streamStored := cache.Get("diff")
+
+			assert.Equal(t, tt.stored, sendStored)
+			assert.Equal(t, tt.stored, streamStored)
+		})
+	}
+}
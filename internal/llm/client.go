@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+
+	"github.com/openai/openai-go/option"
 )
 
 type LLMTokenUsage struct {
@@ -15,6 +17,11 @@ type LLMTokenUsage struct {
 type LLMSendResponse struct {
 	Content string
 	Usage   LLMTokenUsage
+	// AnsweredBy is the provider:model of the fallback target that answered,
+	// empty without fallback.
+	AnsweredBy string
+	// Cached is set when the response comes from the response cache.
+	Cached bool
 }
 
 type LLMStreamEventType string
@@ -29,6 +36,11 @@ type LLMStreamEvent struct {
 	Content string
 	Usage   LLMTokenUsage
 	Type    LLMStreamEventType
+	// Err is the failure of an error event, Content holds its message.
+	Err error
+	// AnsweredBy and Cached are set like in LLMSendResponse.
+	AnsweredBy string
+	Cached     bool
 }
 
 type LLMClient interface {
@@ -39,24 +51,79 @@ type LLMClient interface {
 type LLMProvider string
 
 const (
-	LLMProviderOpenAI LLMProvider = "openai"
-	LLMProviderOllama LLMProvider = "ollama"
+	LLMProviderOpenAI    LLMProvider = "openai"
+	LLMProviderOllama    LLMProvider = "ollama"
+	LLMProviderAnthropic LLMProvider = "anthropic"
+	LLMProviderGemini    LLMProvider = "gemini"
+	LLMProviderAzure     LLMProvider = "azure-openai"
+	LLMProviderReplay    LLMProvider = "replay"
+	LLMProviderMock      LLMProvider = "mock"
 )
 
-var LLMProviders = []LLMProvider{LLMProviderOpenAI, LLMProviderOllama}
+var LLMProviders = []LLMProvider{LLMProviderOpenAI, LLMProviderOllama, LLMProviderAnthropic, LLMProviderGemini, LLMProviderAzure, LLMProviderReplay, LLMProviderMock}
 
 type LLMClientOptions struct {
-	Model string
+	Model      string
+	Generation GenerationOptions
+	OpenAI     OpenAIOptions
+	Azure      AzureOpenAIOptions
+	Replay     ReplayOptions
+	Mock       MockOptions
+}
+
+// GenerationOptions are sampling parameters shared by every provider, nil or
+// zero values keep the provider defaults.
+type GenerationOptions struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int64
+	Seed        *int64
+	Stop        []string
+}
+
+func (g GenerationOptions) isZero() bool {
+	return g.Temperature == nil && g.TopP == nil && g.MaxTokens == 0 && g.Seed == nil && len(g.Stop) == 0
+}
+
+// OpenAIOptions targets any OpenAI-compatible endpoint (vLLM, LM Studio,
+// LiteLLM, OpenRouter, gateways...) with the openai provider.
+type OpenAIOptions struct {
+	BaseURL      string
+	Organization string
+	Project      string
+	Headers      map[string]string
 }
 
+func (o OpenAIOptions) requestOptions() []option.RequestOption {
+	var requestOpts []option.RequestOption
+	if o.BaseURL != "" {
+		requestOpts = append(requestOpts, option.WithBaseURL(o.BaseURL))
+	}
+	if o.Organization != "" {
+		requestOpts = append(requestOpts, option.WithOrganization(o.Organization))
+	}
+	if o.Project != "" {
+		requestOpts = append(requestOpts, option.WithProject(o.Project))
+	}
+	for key, value := range o.Headers {
+		requestOpts = append(requestOpts, option.WithHeader(key, value))
+	}
+	return requestOpts
+}
+
+// withoutSDKRetries leaves retries to NewRetryingClient, which applies the
+// same policy to every provider.
+var withoutSDKRetries = option.WithMaxRetries(0)
+
 func NewClient(provider LLMProvider, opts LLMClientOptions) (LLMClient, error) {
 	switch provider {
 	case LLMProviderOpenAI:
 		apiKey, exists := os.LookupEnv("OPENAI_API_KEY")
-		if !exists || apiKey == "" {
+		// self-hosted OpenAI-compatible servers often don't require any key
+		if (!exists || apiKey == "") && opts.OpenAI.BaseURL == "" {
 			return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
 		}
-		return newOpenAIClient(apiKey, opts.Model), nil
+		return newOpenAIClient(apiKey, opts.Model, opts.Generation, append(opts.OpenAI.requestOptions(), withoutSDKRetries)...), nil
 	case LLMProviderOllama:
 		ollameEndpoint, exists := os.LookupEnv("OLLAMA_ENDPOINT")
 		if !exists || ollameEndpoint == "" {
@@ -66,7 +133,39 @@ func NewClient(provider LLMProvider, opts LLMClientOptions) (LLMClient, error) {
 		if err != nil {
 			return nil, fmt.Errorf("OLLAMA_ENDPOINT URL is invalid: %v", err)
 		}
-		return newOllamaClient(*localEndpoint, opts.Model), nil
+		return newOllamaClient(*localEndpoint, opts.Model, opts.Generation), nil
+	case LLMProviderAnthropic:
+		apiKey, exists := os.LookupEnv("ANTHROPIC_API_KEY")
+		if !exists || apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is not set")
+		}
+		return newAnthropicClient(apiKey, opts.Model, opts.Generation), nil
+	case LLMProviderGemini:
+		apiKey, exists := os.LookupEnv("GEMINI_API_KEY")
+		if !exists || apiKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY environment variable is not set")
+		}
+		return newGeminiClient(apiKey, opts.Model, opts.Generation), nil
+	case LLMProviderAzure:
+		if opts.Azure.Endpoint == "" {
+			return nil, fmt.Errorf("azure openai endpoint is not set")
+		}
+		credential := azureOpenAICredential{
+			apiKey:      os.Getenv("AZURE_OPENAI_API_KEY"),
+			bearerToken: os.Getenv("AZURE_OPENAI_AD_TOKEN"),
+		}
+		if credential.apiKey == "" && credential.bearerToken == "" {
+			return nil, fmt.Errorf("AZURE_OPENAI_API_KEY or AZURE_OPENAI_AD_TOKEN environment variable is not set")
+		}
+		azureOpts := opts.Azure
+		if azureOpts.Deployment == "" {
+			azureOpts.Deployment = opts.Model
+		}
+		return newAzureOpenAIClient(azureOpts, credential, opts.Generation, withoutSDKRetries)
+	case LLMProviderReplay:
+		return newReplayClient(opts.Replay)
+	case LLMProviderMock:
+		return newMockClient(opts.Model, opts.Mock)
 	default:
 		return nil, fmt.Errorf("%s: invalid provider", provider)
 	}
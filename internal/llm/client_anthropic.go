@@ -0,0 +1,333 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	ANTHROPIC_DEFAULT_BASE_URL   = "https://api.anthropic.com"
+	ANTHROPIC_API_VERSION        = "2023-06-01"
+	ANTHROPIC_DEFAULT_MAX_TOKENS = 4096
+)
+
+type LLMClientAnthropic LLMClient
+
+type llmClientAnthropic struct {
+	client     anthropicClient
+	model      string
+	generation GenerationOptions
+}
+
+type anthropicClient interface {
+	New(ctx context.Context, body anthropicMessageParams) (*anthropicMessage, error)
+	NewStreaming(ctx context.Context, body anthropicMessageParams) anthropicMessageStream
+}
+
+type anthropicMessageStream interface {
+	Next() bool
+	Current() anthropicStreamEvent
+	Close() error
+	Err() error
+}
+
+type anthropicMessageParam struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicMessageParams struct {
+	Model         string                  `json:"model"`
+	System        string                  `json:"system,omitempty"`
+	Messages      []anthropicMessageParam `json:"messages"`
+	MaxTokens     int64                   `json:"max_tokens"`
+	Temperature   *float64                `json:"temperature,omitempty"`
+	TopP          *float64                `json:"top_p,omitempty"`
+	StopSequences []string                `json:"stop_sequences,omitempty"`
+	Stream        bool                    `json:"stream,omitempty"`
+}
+
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+type anthropicMessage struct {
+	ID      string                  `json:"id"`
+	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicStreamDelta struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicStreamEvent struct {
+	Type    string               `json:"type"`
+	Message anthropicMessage     `json:"message"`
+	Delta   anthropicStreamDelta `json:"delta"`
+	Usage   anthropicUsage       `json:"usage"`
+	Error   anthropicError       `json:"error"`
+}
+
+type anthropicClientOption func(*defaultAnthropicClient)
+
+func withAnthropicBaseURL(baseURL string) anthropicClientOption {
+	return func(c *defaultAnthropicClient) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func withAnthropicHTTPClient(httpClient *http.Client) anthropicClientOption {
+	return func(c *defaultAnthropicClient) {
+		c.httpClient = httpClient
+	}
+}
+
+type defaultAnthropicClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (c *defaultAnthropicClient) do(ctx context.Context, body anthropicMessageParams) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", ANTHROPIC_API_VERSION)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		return nil, decodeAnthropicError(res)
+	}
+	return res, nil
+}
+
+func decodeAnthropicError(res *http.Response) error {
+	raw, _ := io.ReadAll(res.Body)
+	var body struct {
+		Error anthropicError `json:"error"`
+	}
+	apiErr := &LLMAPIError{
+		StatusCode: res.StatusCode,
+		Message:    fmt.Sprintf("anthropic error (%d): %s", res.StatusCode, strings.TrimSpace(string(raw))),
+		RetryAfter: parseRetryAfter(res.Header),
+	}
+	if err := json.Unmarshal(raw, &body); err == nil && body.Error.Message != "" {
+		apiErr.Type = body.Error.Type
+		apiErr.Message = fmt.Sprintf("anthropic error (%d %s): %s", res.StatusCode, body.Error.Type, body.Error.Message)
+	}
+	return apiErr
+}
+
+func (c *defaultAnthropicClient) New(ctx context.Context, body anthropicMessageParams) (*anthropicMessage, error) {
+	res, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var msg anthropicMessage
+	if err := json.NewDecoder(res.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to decode anthropic response: %v", err)
+	}
+	return &msg, nil
+}
+
+func (c *defaultAnthropicClient) NewStreaming(ctx context.Context, body anthropicMessageParams) anthropicMessageStream {
+	body.Stream = true
+	res, err := c.do(ctx, body)
+	if err != nil {
+		return &defaultAnthropicMessageStream{err: err}
+	}
+	return &defaultAnthropicMessageStream{reader: newSSEReader(res.Body)}
+}
+
+type defaultAnthropicMessageStream struct {
+	reader  *sseReader
+	current anthropicStreamEvent
+	err     error
+}
+
+func (s *defaultAnthropicMessageStream) Next() bool {
+	if s.err != nil || s.reader == nil {
+		return false
+	}
+	data, ok := s.reader.Next()
+	if !ok {
+		s.err = s.reader.Err()
+		return false
+	}
+	var event anthropicStreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		s.err = fmt.Errorf("failed to decode anthropic stream event: %v", err)
+		return false
+	}
+	if event.Type == "error" {
+		s.err = &LLMAPIError{
+			Type:    event.Error.Type,
+			Message: fmt.Sprintf("anthropic error (%s): %s", event.Error.Type, event.Error.Message),
+		}
+		return false
+	}
+	s.current = event
+	return true
+}
+
+func (s *defaultAnthropicMessageStream) Current() anthropicStreamEvent {
+	return s.current
+}
+
+func (s *defaultAnthropicMessageStream) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+	return s.reader.Close()
+}
+
+func (s *defaultAnthropicMessageStream) Err() error {
+	return s.err
+}
+
+func newAnthropicClient(apiKey string, model string, generation GenerationOptions, opts ...anthropicClientOption) LLMClientAnthropic {
+	client := &defaultAnthropicClient{
+		apiKey:     apiKey,
+		baseURL:    ANTHROPIC_DEFAULT_BASE_URL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return &llmClientAnthropic{
+		client:     client,
+		model:      model,
+		generation: generation,
+	}
+}
+
+// toAnthropicParams lifts system messages into the top-level system field,
+// the Messages API only accepts user and assistant turns. It has no seed.
+func (ai *llmClientAnthropic) toAnthropicParams(messages []Message) anthropicMessageParams {
+	var systemPrompts []string
+	var anthropicMessages []anthropicMessageParam
+	for _, msg := range messages {
+		switch msg.Role {
+		case System:
+			systemPrompts = append(systemPrompts, msg.Content)
+		case Assistant:
+			anthropicMessages = append(anthropicMessages, anthropicMessageParam{Role: "assistant", Content: msg.Content})
+		default:
+			anthropicMessages = append(anthropicMessages, anthropicMessageParam{Role: "user", Content: msg.Content})
+		}
+	}
+	maxTokens := int64(ANTHROPIC_DEFAULT_MAX_TOKENS)
+	if ai.generation.MaxTokens > 0 {
+		maxTokens = ai.generation.MaxTokens
+	}
+	return anthropicMessageParams{
+		Model:         ai.model,
+		System:        strings.Join(systemPrompts, "\n\n"),
+		Messages:      anthropicMessages,
+		MaxTokens:     maxTokens,
+		Temperature:   ai.generation.Temperature,
+		TopP:          ai.generation.TopP,
+		StopSequences: ai.generation.Stop,
+	}
+}
+
+func (ai *llmClientAnthropic) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	res, err := ai.client.New(ctx, ai.toAnthropicParams(messages))
+	if err != nil {
+		return nil, err
+	}
+
+	var content strings.Builder
+	for _, block := range res.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	return &LLMSendResponse{
+		Content: content.String(),
+		Usage: LLMTokenUsage{
+			InputTokens:  res.Usage.InputTokens,
+			OutputTokens: res.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (ai *llmClientAnthropic) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+
+	go func() {
+		defer close(out)
+		aiStream := ai.client.NewStreaming(ctx, ai.toAnthropicParams(messages))
+		defer aiStream.Close()
+
+		var content strings.Builder
+		var usage LLMTokenUsage
+		for aiStream.Next() {
+			event := aiStream.Current()
+			switch event.Type {
+			case "message_start":
+				usage.InputTokens = event.Message.Usage.InputTokens
+				usage.OutputTokens = event.Message.Usage.OutputTokens
+			case "content_block_delta":
+				if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+					content.WriteString(event.Delta.Text)
+					out <- LLMStreamEvent{
+						Type:    LLMStreamEventTypeMessage,
+						Content: event.Delta.Text,
+					}
+				}
+			case "message_delta":
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		}
+
+		if err := aiStream.Err(); err != nil {
+			out <- LLMStreamEvent{
+				Type:    LLMStreamEventTypeError,
+				Content: err.Error(),
+				Err:     err,
+			}
+			return
+		}
+
+		out <- LLMStreamEvent{
+			Type:    LLMStreamEventTypeComplete,
+			Content: content.String(),
+			Usage:   usage,
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,308 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type anthropicMockClient struct {
+	mock.Mock
+}
+
+func (m *anthropicMockClient) New(ctx context.Context, body anthropicMessageParams) (*anthropicMessage, error) {
+	args := m.Called(ctx, body)
+	resVal := args.Get(0)
+	if resVal == nil {
+		return nil, args.Error(1)
+	}
+	return resVal.(*anthropicMessage), args.Error(1)
+}
+
+func (m *anthropicMockClient) NewStreaming(ctx context.Context, body anthropicMessageParams) anthropicMessageStream {
+	args := m.Called(ctx, body)
+	return args.Get(0).(anthropicMessageStream)
+}
+
+type anthropicMockStream struct {
+	events []anthropicStreamEvent
+	index  int
+	err    error
+}
+
+func (s *anthropicMockStream) Next() bool {
+	if s.index >= len(s.events) {
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *anthropicMockStream) Current() anthropicStreamEvent {
+	return s.events[s.index-1]
+}
+
+func (s *anthropicMockStream) Close() error {
+	return nil
+}
+
+func (s *anthropicMockStream) Err() error {
+	return s.err
+}
+
+func newAnthropicMockClient(model string) *llmClientAnthropic {
+	return &llmClientAnthropic{
+		client: new(anthropicMockClient),
+		model:  model,
+	}
+}
+
+func TestSendAnthropic_Success(t *testing.T) {
+	messages := []Message{
+		{Role: System, Content: "This is a system message"},
+		{Role: User, Content: "Hello"},
+		{Role: Assistant, Content: "Hi, how can I help?"},
+		{Role: User, Content: "Review this"},
+	}
+	client := newAnthropicMockClient("claude-model")
+	client.client.(*anthropicMockClient).
+		On("New", t.Context(), anthropicMessageParams{
+			Model:  "claude-model",
+			System: "This is a system message",
+			Messages: []anthropicMessageParam{
+				{Role: "user", Content: "Hello"},
+				{Role: "assistant", Content: "Hi, how can I help?"},
+				{Role: "user", Content: "Review this"},
+			},
+			MaxTokens: ANTHROPIC_DEFAULT_MAX_TOKENS,
+		}).
+		Return(&anthropicMessage{
+			Content: []anthropicContentBlock{
+				{Type: "text", Text: "Anthropic "},
+				{Type: "text", Text: "response"},
+			},
+			Usage: anthropicUsage{InputTokens: 50, OutputTokens: 100},
+		}, nil)
+
+	res, err := client.Send(t.Context(), messages)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &LLMSendResponse{
+		Content: "Anthropic response",
+		Usage: LLMTokenUsage{
+			InputTokens:  50,
+			OutputTokens: 100,
+		},
+	}, res)
+}
+
+func TestSendAnthropic_Error(t *testing.T) {
+	client := newAnthropicMockClient("claude-model")
+	client.client.(*anthropicMockClient).
+		On("New", t.Context(), mock.AnythingOfType("llm.anthropicMessageParams")).
+		Return(nil, errors.New("failed to send message"))
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+
+	assert.EqualError(t, err, "failed to send message")
+	assert.Nil(t, res)
+}
+
+func TestStreamAnthropic_Success(t *testing.T) {
+	client := newAnthropicMockClient("claude-model")
+	client.client.(*anthropicMockClient).
+		On("NewStreaming", t.Context(), mock.AnythingOfType("llm.anthropicMessageParams")).
+		Return(&anthropicMockStream{events: []anthropicStreamEvent{
+			{Type: "message_start", Message: anthropicMessage{Usage: anthropicUsage{InputTokens: 12, OutputTokens: 1}}},
+			{Type: "content_block_start"},
+			{Type: "content_block_delta", Delta: anthropicStreamDelta{Type: "text_delta", Text: "hello "}},
+			{Type: "content_block_delta", Delta: anthropicStreamDelta{Type: "text_delta", Text: "back"}},
+			{Type: "content_block_stop"},
+			{Type: "message_delta", Usage: anthropicUsage{OutputTokens: 7}},
+			{Type: "message_stop"},
+		}})
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "hello "}, events[0])
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "back"}, events[1])
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeComplete,
+		Content: "hello back",
+		Usage: LLMTokenUsage{
+			InputTokens:  12,
+			OutputTokens: 7,
+		},
+	}, events[2])
+}
+
+func TestStreamAnthropic_Error(t *testing.T) {
+	streamErr := errors.New("failed to stream content")
+	client := newAnthropicMockClient("claude-model")
+	client.client.(*anthropicMockClient).
+		On("NewStreaming", t.Context(), mock.AnythingOfType("llm.anthropicMessageParams")).
+		Return(&anthropicMockStream{
+			events: []anthropicStreamEvent{
+				{Type: "content_block_delta", Delta: anthropicStreamDelta{Type: "text_delta", Text: "hello"}},
+			},
+			err: streamErr,
+		})
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "hello"}, events[0])
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeError, Content: "failed to stream content", Err: streamErr}, events[1])
+}
+
+func TestSendAnthropic_HttpSuccess(t *testing.T) {
+	var received anthropicMessageParams
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("x-api-key"))
+		assert.Equal(t, ANTHROPIC_API_VERSION, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"hello world"}],"usage":{"input_tokens":5,"output_tokens":3}}`))
+	}))
+	defer ts.Close()
+
+	client := newAnthropicClient("secret", "model", GenerationOptions{}, withAnthropicHTTPClient(ts.Client()), withAnthropicBaseURL(ts.URL))
+
+	res, err := client.Send(t.Context(), []Message{
+		{Role: System, Content: "system"},
+		{Role: User, Content: "Hello"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{
+		Content: "hello world",
+		Usage:   LLMTokenUsage{InputTokens: 5, OutputTokens: 3},
+	}, res)
+	assert.Equal(t, "system", received.System)
+	assert.Equal(t, []anthropicMessageParam{{Role: "user", Content: "Hello"}}, received.Messages)
+	assert.False(t, received.Stream)
+}
+
+func TestSendAnthropic_HttpError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer ts.Close()
+
+	client := newAnthropicClient("bad", "model", GenerationOptions{}, withAnthropicHTTPClient(ts.Client()), withAnthropicBaseURL(ts.URL))
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+
+	assert.Nil(t, res)
+	assert.EqualError(t, err, "anthropic error (401 authentication_error): invalid x-api-key")
+}
+
+func TestStreamAnthropic_HttpSuccess(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received anthropicMessageParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		assert.True(t, received.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+			return
+		}
+
+		chunks := []string{
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"content\":[],\"usage\":{\"input_tokens\":5,\"output_tokens\":1}}}\n\n",
+			"event: ping\ndata: {\"type\":\"ping\"}\n\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hello\"}}\n\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\" world\"}}\n\n",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":3}}\n\n",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+		}
+		for _, chunk := range chunks {
+			if _, err := w.Write([]byte(chunk)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}))
+	defer ts.Close()
+
+	client := newAnthropicClient("", "model", GenerationOptions{}, withAnthropicHTTPClient(ts.Client()), withAnthropicBaseURL(ts.URL))
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	assert.Equal(t, "hello", events[0].Content)
+	assert.Equal(t, " world", events[1].Content)
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeComplete,
+		Content: "hello world",
+		Usage:   LLMTokenUsage{InputTokens: 5, OutputTokens: 3},
+	}, events[2])
+}
+
+func TestStreamAnthropic_HttpErrorEvent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer ts.Close()
+
+	client := newAnthropicClient("", "model", GenerationOptions{}, withAnthropicHTTPClient(ts.Client()), withAnthropicBaseURL(ts.URL))
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 1)
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeError,
+		Content: "anthropic error (overloaded_error): Overloaded",
+		Err: &LLMAPIError{
+			Type:    "overloaded_error",
+			Message: "anthropic error (overloaded_error): Overloaded",
+		},
+	}, events[0])
+}
+
+func TestToAnthropicParams_WithGenerationOptions(t *testing.T) {
+	temperature, seed := 0.3, int64(1)
+	ai := &llmClientAnthropic{model: "claude-model", generation: GenerationOptions{
+		Temperature: &temperature,
+		MaxTokens:   1024,
+		Seed:        &seed,
+		Stop:        []string{"END"},
+	}}
+
+	params := ai.toAnthropicParams([]Message{{Role: User, Content: "Hello"}})
+
+	assert.Equal(t, anthropicMessageParams{
+		Model:         "claude-model",
+		Messages:      []anthropicMessageParam{{Role: "user", Content: "Hello"}},
+		MaxTokens:     1024,
+		Temperature:   &temperature,
+		StopSequences: []string{"END"},
+	}, params)
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+const AZURE_OPENAI_DEFAULT_API_VERSION = "2024-10-21"
+
+// AzureOpenAIOptions routes requests to an Azure OpenAI deployment, the
+// deployment defaults to the model name when empty.
+type AzureOpenAIOptions struct {
+	Endpoint   string
+	Deployment string
+	APIVersion string
+}
+
+type azureOpenAICredential struct {
+	apiKey      string
+	bearerToken string
+}
+
+func (c azureOpenAICredential) requestOptions() []option.RequestOption {
+	if c.bearerToken != "" {
+		return []option.RequestOption{option.WithAPIKey(c.bearerToken)}
+	}
+	// Azure expects the key in api-key, drop any bearer picked up from OPENAI_API_KEY
+	return []option.RequestOption{
+		option.WithHeaderDel("authorization"),
+		option.WithHeader("api-key", c.apiKey),
+	}
+}
+
+func azureOpenAIDeploymentURL(endpoint string, deployment string) (string, error) {
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return "", fmt.Errorf("azure openai endpoint is invalid: %v", err)
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/", strings.TrimRight(endpoint, "/"), url.PathEscape(deployment)), nil
+}
+
+func newAzureOpenAIClient(azureOpts AzureOpenAIOptions, credential azureOpenAICredential, generation GenerationOptions, opts ...option.RequestOption) (LLMClientOpenAI, error) {
+	baseURL, err := azureOpenAIDeploymentURL(azureOpts.Endpoint, azureOpts.Deployment)
+	if err != nil {
+		return nil, err
+	}
+	apiVersion := azureOpts.APIVersion
+	if apiVersion == "" {
+		apiVersion = AZURE_OPENAI_DEFAULT_API_VERSION
+	}
+
+	requestOpts := []option.RequestOption{
+		option.WithBaseURL(baseURL),
+		option.WithQuery("api-version", apiVersion),
+	}
+	requestOpts = append(requestOpts, credential.requestOptions()...)
+	requestOpts = append(requestOpts, opts...)
+
+	return &llmClientOpenAi{
+		client:     &defaultOpenAiClient{client: openai.NewClient(requestOpts...)},
+		model:      azureOpts.Deployment,
+		generation: generation,
+	}, nil
+}
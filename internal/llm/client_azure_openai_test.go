@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAzureTestServer(received **http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"azure response"}}],"usage":{"prompt_tokens":4,"completion_tokens":2}}`))
+	}))
+}
+
+func TestSendAzureOpenAI_WithAPIKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "should-not-leak")
+	var received *http.Request
+	ts := newAzureTestServer(&received)
+	defer ts.Close()
+
+	client, err := newAzureOpenAIClient(
+		AzureOpenAIOptions{Endpoint: ts.URL + "/", Deployment: "gpt-4o-prod", APIVersion: "2024-06-01"},
+		azureOpenAICredential{apiKey: "azure-key"},
+		GenerationOptions{},
+		option.WithHTTPClient(ts.Client()),
+	)
+	require.NoError(t, err)
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{
+		Content: "azure response",
+		Usage:   LLMTokenUsage{InputTokens: 4, OutputTokens: 2},
+	}, res)
+
+	require.NotNil(t, received)
+	assert.Equal(t, "/openai/deployments/gpt-4o-prod/chat/completions", received.URL.Path)
+	assert.Equal(t, "2024-06-01", received.URL.Query().Get("api-version"))
+	assert.Equal(t, "azure-key", received.Header.Get("api-key"))
+	assert.Empty(t, received.Header.Get("Authorization"))
+}
+
+func TestSendAzureOpenAI_WithBearerToken(t *testing.T) {
+	var received *http.Request
+	ts := newAzureTestServer(&received)
+	defer ts.Close()
+
+	client, err := newAzureOpenAIClient(
+		AzureOpenAIOptions{Endpoint: ts.URL, Deployment: "gpt-4o-prod"},
+		azureOpenAICredential{bearerToken: "entra-token"},
+		GenerationOptions{},
+		option.WithHTTPClient(ts.Client()),
+	)
+	require.NoError(t, err)
+
+	_, err = client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+	require.NoError(t, err)
+
+	require.NotNil(t, received)
+	assert.Equal(t, AZURE_OPENAI_DEFAULT_API_VERSION, received.URL.Query().Get("api-version"))
+	assert.Equal(t, "Bearer entra-token", received.Header.Get("Authorization"))
+	assert.Empty(t, received.Header.Get("api-key"))
+}
+
+func TestNewAzureOpenAIClient_InvalidEndpoint(t *testing.T) {
+	client, err := newAzureOpenAIClient(AzureOpenAIOptions{Endpoint: "not a url", Deployment: "d"}, azureOpenAICredential{apiKey: "k"}, GenerationOptions{})
+	assert.Nil(t, client)
+	assert.ErrorContains(t, err, "azure openai endpoint is invalid")
+}
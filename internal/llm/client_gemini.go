@@ -0,0 +1,319 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const GEMINI_DEFAULT_BASE_URL = "https://generativelanguage.googleapis.com"
+
+type LLMClientGemini LLMClient
+
+type llmClientGemini struct {
+	client     geminiClient
+	model      string
+	generation GenerationOptions
+}
+
+type geminiClient interface {
+	GenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) (*geminiGenerateContentResponse, error)
+	StreamGenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) geminiContentStream
+}
+
+type geminiContentStream interface {
+	Next() bool
+	Current() geminiGenerateContentResponse
+	Close() error
+	Err() error
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens int64    `json:"maxOutputTokens,omitempty"`
+	Seed            *int64   `json:"seed,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+}
+
+type geminiGenerateContentParams struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+}
+
+type geminiGenerateContentResponse struct {
+	Candidates    []geminiCandidate   `json:"candidates"`
+	UsageMetadata geminiUsageMetadata `json:"usageMetadata"`
+}
+
+// text concatenates the parts of the first candidate, Gemini may split a
+// single answer into several text parts.
+func (r geminiGenerateContentResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+type geminiClientOption func(*defaultGeminiClient)
+
+func withGeminiBaseURL(baseURL string) geminiClientOption {
+	return func(c *defaultGeminiClient) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func withGeminiHTTPClient(httpClient *http.Client) geminiClientOption {
+	return func(c *defaultGeminiClient) {
+		c.httpClient = httpClient
+	}
+}
+
+type defaultGeminiClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (c *defaultGeminiClient) do(ctx context.Context, model string, method string, query url.Values, body geminiGenerateContentParams) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:%s", c.baseURL, url.PathEscape(model), method)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		return nil, decodeGeminiError(res)
+	}
+	return res, nil
+}
+
+func decodeGeminiError(res *http.Response) error {
+	raw, _ := io.ReadAll(res.Body)
+	var body struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	apiErr := &LLMAPIError{
+		StatusCode: res.StatusCode,
+		Message:    fmt.Sprintf("gemini error (%d): %s", res.StatusCode, strings.TrimSpace(string(raw))),
+		RetryAfter: parseRetryAfter(res.Header),
+	}
+	if err := json.Unmarshal(raw, &body); err == nil && body.Error.Message != "" {
+		apiErr.Type = body.Error.Status
+		apiErr.Message = fmt.Sprintf("gemini error (%d %s): %s", res.StatusCode, body.Error.Status, body.Error.Message)
+	}
+	return apiErr
+}
+
+func (c *defaultGeminiClient) GenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) (*geminiGenerateContentResponse, error) {
+	res, err := c.do(ctx, model, "generateContent", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var content geminiGenerateContentResponse
+	if err := json.NewDecoder(res.Body).Decode(&content); err != nil {
+		return nil, fmt.Errorf("failed to decode gemini response: %v", err)
+	}
+	return &content, nil
+}
+
+func (c *defaultGeminiClient) StreamGenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) geminiContentStream {
+	res, err := c.do(ctx, model, "streamGenerateContent", url.Values{"alt": {"sse"}}, body)
+	if err != nil {
+		return &defaultGeminiContentStream{err: err}
+	}
+	return &defaultGeminiContentStream{reader: newSSEReader(res.Body)}
+}
+
+type defaultGeminiContentStream struct {
+	reader  *sseReader
+	current geminiGenerateContentResponse
+	err     error
+}
+
+func (s *defaultGeminiContentStream) Next() bool {
+	if s.err != nil || s.reader == nil {
+		return false
+	}
+	data, ok := s.reader.Next()
+	if !ok {
+		s.err = s.reader.Err()
+		return false
+	}
+	var chunk geminiGenerateContentResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		s.err = fmt.Errorf("failed to decode gemini stream chunk: %v", err)
+		return false
+	}
+	s.current = chunk
+	return true
+}
+
+func (s *defaultGeminiContentStream) Current() geminiGenerateContentResponse {
+	return s.current
+}
+
+func (s *defaultGeminiContentStream) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+	return s.reader.Close()
+}
+
+func (s *defaultGeminiContentStream) Err() error {
+	return s.err
+}
+
+func newGeminiClient(apiKey string, model string, generation GenerationOptions, opts ...geminiClientOption) LLMClientGemini {
+	client := &defaultGeminiClient{
+		apiKey:     apiKey,
+		baseURL:    GEMINI_DEFAULT_BASE_URL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return &llmClientGemini{
+		client:     client,
+		model:      model,
+		generation: generation,
+	}
+}
+
+// toGeminiParams maps system messages to systemInstruction and the assistant
+// role to Gemini's model role.
+func (ai *llmClientGemini) toGeminiParams(messages []Message) geminiGenerateContentParams {
+	var params geminiGenerateContentParams
+	for _, msg := range messages {
+		switch msg.Role {
+		case System:
+			if params.SystemInstruction == nil {
+				params.SystemInstruction = &geminiContent{}
+			}
+			params.SystemInstruction.Parts = append(params.SystemInstruction.Parts, geminiPart{Text: msg.Content})
+		case Assistant:
+			params.Contents = append(params.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: msg.Content}}})
+		default:
+			params.Contents = append(params.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: msg.Content}}})
+		}
+	}
+	if !ai.generation.isZero() {
+		params.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     ai.generation.Temperature,
+			TopP:            ai.generation.TopP,
+			MaxOutputTokens: ai.generation.MaxTokens,
+			Seed:            ai.generation.Seed,
+			StopSequences:   ai.generation.Stop,
+		}
+	}
+	return params
+}
+
+func (ai *llmClientGemini) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	res, err := ai.client.GenerateContent(ctx, ai.model, ai.toGeminiParams(messages))
+	if err != nil {
+		return nil, err
+	}
+
+	return &LLMSendResponse{
+		Content: res.text(),
+		Usage: LLMTokenUsage{
+			InputTokens:  res.UsageMetadata.PromptTokenCount,
+			OutputTokens: res.UsageMetadata.CandidatesTokenCount,
+		},
+	}, nil
+}
+
+func (ai *llmClientGemini) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+
+	go func() {
+		defer close(out)
+		aiStream := ai.client.StreamGenerateContent(ctx, ai.model, ai.toGeminiParams(messages))
+		defer aiStream.Close()
+
+		var content strings.Builder
+		var usage LLMTokenUsage
+		for aiStream.Next() {
+			chunk := aiStream.Current()
+			// every chunk carries the cumulative usage so far
+			usage = LLMTokenUsage{
+				InputTokens:  chunk.UsageMetadata.PromptTokenCount,
+				OutputTokens: chunk.UsageMetadata.CandidatesTokenCount,
+			}
+			if text := chunk.text(); text != "" {
+				content.WriteString(text)
+				out <- LLMStreamEvent{
+					Type:    LLMStreamEventTypeMessage,
+					Content: text,
+					Usage:   usage,
+				}
+			}
+		}
+
+		if err := aiStream.Err(); err != nil {
+			out <- LLMStreamEvent{
+				Type:    LLMStreamEventTypeError,
+				Content: err.Error(),
+				Err:     err,
+			}
+			return
+		}
+
+		out <- LLMStreamEvent{
+			Type:    LLMStreamEventTypeComplete,
+			Content: content.String(),
+			Usage:   usage,
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,276 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type geminiMockClient struct {
+	mock.Mock
+}
+
+func (m *geminiMockClient) GenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) (*geminiGenerateContentResponse, error) {
+	args := m.Called(ctx, model, body)
+	resVal := args.Get(0)
+	if resVal == nil {
+		return nil, args.Error(1)
+	}
+	return resVal.(*geminiGenerateContentResponse), args.Error(1)
+}
+
+func (m *geminiMockClient) StreamGenerateContent(ctx context.Context, model string, body geminiGenerateContentParams) geminiContentStream {
+	args := m.Called(ctx, model, body)
+	return args.Get(0).(geminiContentStream)
+}
+
+type geminiMockStream struct {
+	chunks []geminiGenerateContentResponse
+	index  int
+	err    error
+}
+
+func (s *geminiMockStream) Next() bool {
+	if s.index >= len(s.chunks) {
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *geminiMockStream) Current() geminiGenerateContentResponse {
+	return s.chunks[s.index-1]
+}
+
+func (s *geminiMockStream) Close() error {
+	return nil
+}
+
+func (s *geminiMockStream) Err() error {
+	return s.err
+}
+
+func newGeminiMockClient(model string) *llmClientGemini {
+	return &llmClientGemini{
+		client: new(geminiMockClient),
+		model:  model,
+	}
+}
+
+func geminiTextChunk(text string, promptTokens int64, candidatesTokens int64) geminiGenerateContentResponse {
+	return geminiGenerateContentResponse{
+		Candidates: []geminiCandidate{
+			{Content: geminiContent{Role: "model", Parts: []geminiPart{{Text: text}}}},
+		},
+		UsageMetadata: geminiUsageMetadata{PromptTokenCount: promptTokens, CandidatesTokenCount: candidatesTokens},
+	}
+}
+
+func TestSendGemini_Success(t *testing.T) {
+	messages := []Message{
+		{Role: System, Content: "This is a system message"},
+		{Role: User, Content: "Hello"},
+		{Role: Assistant, Content: "Hi, how can I help?"},
+		{Role: User, Content: "Review this"},
+	}
+	client := newGeminiMockClient("gemini-model")
+	client.client.(*geminiMockClient).
+		On("GenerateContent", t.Context(), "gemini-model", geminiGenerateContentParams{
+			SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: "This is a system message"}}},
+			Contents: []geminiContent{
+				{Role: "user", Parts: []geminiPart{{Text: "Hello"}}},
+				{Role: "model", Parts: []geminiPart{{Text: "Hi, how can I help?"}}},
+				{Role: "user", Parts: []geminiPart{{Text: "Review this"}}},
+			},
+		}).
+		Return(&geminiGenerateContentResponse{
+			Candidates: []geminiCandidate{
+				{Content: geminiContent{Role: "model", Parts: []geminiPart{{Text: "Gemini "}, {Text: "response"}}}},
+			},
+			UsageMetadata: geminiUsageMetadata{PromptTokenCount: 50, CandidatesTokenCount: 100},
+		}, nil)
+
+	res, err := client.Send(t.Context(), messages)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &LLMSendResponse{
+		Content: "Gemini response",
+		Usage: LLMTokenUsage{
+			InputTokens:  50,
+			OutputTokens: 100,
+		},
+	}, res)
+}
+
+func TestSendGemini_Error(t *testing.T) {
+	client := newGeminiMockClient("gemini-model")
+	client.client.(*geminiMockClient).
+		On("GenerateContent", t.Context(), "gemini-model", mock.AnythingOfType("llm.geminiGenerateContentParams")).
+		Return(nil, errors.New("failed to send message"))
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+
+	assert.EqualError(t, err, "failed to send message")
+	assert.Nil(t, res)
+}
+
+func TestStreamGemini_Success(t *testing.T) {
+	client := newGeminiMockClient("gemini-model")
+	client.client.(*geminiMockClient).
+		On("StreamGenerateContent", t.Context(), "gemini-model", mock.AnythingOfType("llm.geminiGenerateContentParams")).
+		Return(&geminiMockStream{chunks: []geminiGenerateContentResponse{
+			geminiTextChunk("hello ", 10, 2),
+			geminiTextChunk("back", 10, 4),
+			geminiTextChunk("", 10, 4),
+		}})
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	assert.Equal(t, "hello ", events[0].Content)
+	assert.Equal(t, LLMStreamEventTypeMessage, events[0].Type)
+	assert.Equal(t, "back", events[1].Content)
+	assert.Equal(t, LLMStreamEventTypeMessage, events[1].Type)
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeComplete,
+		Content: "hello back",
+		Usage: LLMTokenUsage{
+			InputTokens:  10,
+			OutputTokens: 4,
+		},
+	}, events[2])
+}
+
+func TestStreamGemini_Error(t *testing.T) {
+	streamErr := errors.New("failed to stream content")
+	client := newGeminiMockClient("gemini-model")
+	client.client.(*geminiMockClient).
+		On("StreamGenerateContent", t.Context(), "gemini-model", mock.AnythingOfType("llm.geminiGenerateContentParams")).
+		Return(&geminiMockStream{
+			chunks: []geminiGenerateContentResponse{geminiTextChunk("hello", 1, 1)},
+			err:    streamErr,
+		})
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "hello", events[0].Content)
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeError, Content: "failed to stream content", Err: streamErr}, events[1])
+}
+
+func TestSendGemini_HttpSuccess(t *testing.T) {
+	var received geminiGenerateContentParams
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-model:generateContent", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("x-goog-api-key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hello world"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`))
+	}))
+	defer ts.Close()
+
+	client := newGeminiClient("secret", "gemini-model", GenerationOptions{}, withGeminiHTTPClient(ts.Client()), withGeminiBaseURL(ts.URL))
+
+	res, err := client.Send(t.Context(), []Message{
+		{Role: System, Content: "system"},
+		{Role: User, Content: "Hello"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{
+		Content: "hello world",
+		Usage:   LLMTokenUsage{InputTokens: 5, OutputTokens: 3},
+	}, res)
+	assert.Equal(t, &geminiContent{Parts: []geminiPart{{Text: "system"}}}, received.SystemInstruction)
+	assert.Equal(t, []geminiContent{{Role: "user", Parts: []geminiPart{{Text: "Hello"}}}}, received.Contents)
+}
+
+func TestSendGemini_HttpError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`))
+	}))
+	defer ts.Close()
+
+	client := newGeminiClient("bad", "gemini-model", GenerationOptions{}, withGeminiHTTPClient(ts.Client()), withGeminiBaseURL(ts.URL))
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+
+	assert.Nil(t, res)
+	assert.EqualError(t, err, "gemini error (400 INVALID_ARGUMENT): API key not valid.")
+}
+
+func TestStreamGemini_HttpSuccess(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-model:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+			return
+		}
+
+		chunks := []string{
+			`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":1}}` + "\r\n\r\n",
+			`data: {"candidates":[{"content":{"role":"model","parts":[{"text":" world"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3}}` + "\r\n\r\n",
+		}
+		for _, chunk := range chunks {
+			if _, err := w.Write([]byte(chunk)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}))
+	defer ts.Close()
+
+	client := newGeminiClient("", "gemini-model", GenerationOptions{}, withGeminiHTTPClient(ts.Client()), withGeminiBaseURL(ts.URL))
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	assert.Equal(t, "hello", events[0].Content)
+	assert.Equal(t, " world", events[1].Content)
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeComplete,
+		Content: "hello world",
+		Usage:   LLMTokenUsage{InputTokens: 5, OutputTokens: 3},
+	}, events[2])
+}
+
+func TestToGeminiParams_WithGenerationOptions(t *testing.T) {
+	topP, seed := 0.8, int64(9)
+	ai := &llmClientGemini{model: "gemini-model", generation: GenerationOptions{
+		TopP:      &topP,
+		MaxTokens: 2048,
+		Seed:      &seed,
+	}}
+
+	params := ai.toGeminiParams([]Message{{Role: User, Content: "Hello"}})
+
+	assert.Nil(t, params.SystemInstruction)
+	assert.Equal(t, &geminiGenerationConfig{
+		TopP:            &topP,
+		MaxOutputTokens: 2048,
+		Seed:            &seed,
+	}, params.GenerationConfig)
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const MOCK_DEFAULT_RESPONSE = "Mock review of {{.Files}} files, +{{.Additions}} -{{.Deletions}} lines, by {{.Model}}."
+
+// MockOptions configure the mock provider, which answers without any model
+// for demos and tests. Response is a text/template rendered with
+// MockTemplateData, read from ResponseFile when set. A streamed answer sends
+// a word every ChunkDelay. ErrorStatus makes the first Failures requests fail
+// with that HTTP status, every request when Failures is 0.
+type MockOptions struct {
+	Response     string
+	ResponseFile string
+	ChunkDelay   time.Duration
+	ErrorStatus  int
+	Failures     int
+}
+
+// MockTemplateData is given to the response template, the diff stats come
+// from the last user message.
+type MockTemplateData struct {
+	Model       string
+	Messages    []Message
+	LastMessage string
+	Files       int
+	Additions   int
+	Deletions   int
+}
+
+type llmClientMock struct {
+	model    string
+	response *template.Template
+	opts     MockOptions
+	mu       sync.Mutex
+	requests int
+}
+
+func newMockClient(model string, opts MockOptions) (LLMClient, error) {
+	response := opts.Response
+	if opts.ResponseFile != "" {
+		raw, err := os.ReadFile(opts.ResponseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mock response file: %v", err)
+		}
+		response = string(raw)
+	}
+	if response == "" {
+		response = MOCK_DEFAULT_RESPONSE
+	}
+	tmpl, err := template.New("mock").Parse(response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mock response template: %v", err)
+	}
+	if opts.ErrorStatus != 0 && (opts.ErrorStatus < 400 || opts.ErrorStatus > 599) {
+		return nil, fmt.Errorf("invalid mock error status %d, must be between 400 and 599", opts.ErrorStatus)
+	}
+	if model == "" {
+		model = "mock"
+	}
+	return &llmClientMock{model: model, response: tmpl, opts: opts}, nil
+}
+
+// answer renders the response to messages, or the injected error.
+func (c *llmClientMock) answer(messages []Message) (string, error) {
+	c.mu.Lock()
+	c.requests++
+	failing := c.opts.ErrorStatus != 0 && (c.opts.Failures == 0 || c.requests <= c.opts.Failures)
+	c.mu.Unlock()
+	if failing {
+		return "", &LLMAPIError{
+			StatusCode: c.opts.ErrorStatus,
+			Message:    fmt.Sprintf("mock error %d %s", c.opts.ErrorStatus, http.StatusText(c.opts.ErrorStatus)),
+		}
+	}
+
+	data := MockTemplateData{Model: c.model, Messages: messages}
+	for _, message := range messages {
+		if message.Role == User {
+			data.LastMessage = message.Content
+		}
+	}
+	for _, line := range strings.Split(data.LastMessage, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			data.Files++
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			data.Additions++
+		case strings.HasPrefix(line, "-"):
+			data.Deletions++
+		}
+	}
+
+	var content bytes.Buffer
+	if err := c.response.Execute(&content, data); err != nil {
+		return "", fmt.Errorf("failed to render mock response: %v", err)
+	}
+	return content.String(), nil
+}
+
+// mockUsage estimates the tokens a model would have used, for the usage report.
+func mockUsage(messages []Message, content string) LLMTokenUsage {
+	var usage LLMTokenUsage
+	for _, message := range messages {
+		usage.InputTokens += int64(HeuristicTokenizer{}.CountTokens(message.Content))
+	}
+	usage.OutputTokens = int64(HeuristicTokenizer{}.CountTokens(content))
+	return usage
+}
+
+func (c *llmClientMock) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	content, err := c.answer(messages)
+	if err != nil {
+		return nil, err
+	}
+	return &LLMSendResponse{Content: content, Usage: mockUsage(messages, content)}, nil
+}
+
+func (c *llmClientMock) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		content, err := c.answer(messages)
+		if err != nil {
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+			return
+		}
+
+		for _, word := range strings.SplitAfter(content, " ") {
+			if err := sleepContext(ctx, c.opts.ChunkDelay); err != nil {
+				out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+				return
+			}
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: word}
+		}
+		out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: content, Usage: mockUsage(messages, content)}
+	}()
+	return out
+}
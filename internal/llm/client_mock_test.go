@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mockTestDiff = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n-old\n+new\n+added\n"
+
+func TestMockClient_Send(t *testing.T) {
+	client, err := NewClient(LLMProviderMock, LLMClientOptions{Model: "demo"})
+	require.NoError(t, err)
+
+	res, err := client.Send(t.Context(), []Message{{Role: System, Content: "prompt"}, {Role: User, Content: mockTestDiff}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Mock review of 1 files, +2 -1 lines, by demo.", res.Content)
+	assert.Positive(t, res.Usage.InputTokens)
+	assert.Positive(t, res.Usage.OutputTokens)
+}
+
+func TestMockClient_ResponseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.md")
+	require.NoError(t, os.WriteFile(path, []byte("You asked: {{.LastMessage}}"), 0o600))
+	client, err := NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{Response: "ignored", ResponseFile: path}})
+	require.NoError(t, err)
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "why?"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "You asked: why?", res.Content)
+}
+
+func TestMockClient_Stream(t *testing.T) {
+	client, err := NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{Response: "Looks good to me", ChunkDelay: time.Millisecond}})
+	require.NoError(t, err)
+
+	events := collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "diff"}}))
+
+	require.Len(t, events, 5)
+	for i, word := range []string{"Looks ", "good ", "to ", "me"} {
+		assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: word}, events[i])
+	}
+	assert.Equal(t, LLMStreamEventTypeComplete, events[4].Type)
+	assert.Equal(t, "Looks good to me", events[4].Content)
+}
+
+func TestMockClient_InjectedErrors(t *testing.T) {
+	client, err := NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{Response: "ok", ErrorStatus: 429, Failures: 2}})
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := client.Send(t.Context(), nil)
+		assert.EqualError(t, err, "mock error 429 Too Many Requests")
+		assert.Equal(t, LLMErrorRateLimit, ClassifyError(err))
+	}
+	events := collectStreamEvents(client.Stream(t.Context(), nil))
+	assert.Equal(t, LLMStreamEventTypeComplete, events[len(events)-1].Type)
+
+	client, err = NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{ErrorStatus: 503}})
+	require.NoError(t, err)
+	events = collectStreamEvents(client.Stream(t.Context(), nil))
+	require.Len(t, events, 1)
+	assert.Equal(t, LLMStreamEventTypeError, events[0].Type)
+	assert.Equal(t, LLMErrorServer, ClassifyError(events[0].Err))
+}
+
+func TestNewClient_Mock_InvalidOptions(t *testing.T) {
+	_, err := NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{Response: "{{.Missing"}})
+	assert.ErrorContains(t, err, "invalid mock response template")
+
+	_, err = NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{ResponseFile: filepath.Join(t.TempDir(), "missing.md")}})
+	assert.ErrorContains(t, err, "failed to read mock response file")
+
+	_, err = NewClient(LLMProviderMock, LLMClientOptions{Mock: MockOptions{ErrorStatus: 200}})
+	assert.EqualError(t, err, "invalid mock error status 200, must be between 400 and 599")
+}
@@ -14,15 +14,17 @@ type ollamaClient interface {
 }
 
 type llmClientOllama struct {
-	client ollamaClient
-	model  string
+	client     ollamaClient
+	model      string
+	generation GenerationOptions
 }
 type LLMClientOllama LLMClient
 
-func newOllamaClient(localEndpoint url.URL, model string) LLMClientOllama {
+func newOllamaClient(localEndpoint url.URL, model string, generation GenerationOptions) LLMClientOllama {
 	return &llmClientOllama{
-		client: api.NewClient(&localEndpoint, http.DefaultClient),
-		model:  model,
+		client:     api.NewClient(&localEndpoint, http.DefaultClient),
+		model:      model,
+		generation: generation,
 	}
 }
 
@@ -36,9 +38,10 @@ func (ai *llmClientOllama) Send(ctx context.Context, messages []Message) (*LLMSe
 		case LLMStreamEventTypeComplete:
 			return &LLMSendResponse{
 				Content: fullResult,
+				Usage:   event.Usage,
 			}, nil
 		case LLMStreamEventTypeError:
-			return nil, fmt.Errorf("ollama error: %s", event.Content)
+			return nil, fmt.Errorf("ollama error: %w", event.Err)
 		}
 	}
 	return &LLMSendResponse{
@@ -59,10 +62,18 @@ func (ai *llmClientOllama) chat(ctx context.Context, messages []Message) <-chan
 			Model:    ai.model,
 			Messages: ai.toOllamaMessages(messages),
 			Stream:   &stream,
+			Options:  ai.toOllamaOptions(),
 		}, func(resp api.ChatResponse) error {
 			out <- LLMStreamEvent{Content: resp.Message.Content, Type: LLMStreamEventTypeMessage}
 			if resp.Done {
-				out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete}
+				// only the final chunk carries the metrics
+				out <- LLMStreamEvent{
+					Type: LLMStreamEventTypeComplete,
+					Usage: LLMTokenUsage{
+						InputTokens:  int64(resp.PromptEvalCount),
+						OutputTokens: int64(resp.EvalCount),
+					},
+				}
 			}
 			return nil
 		})
@@ -71,6 +82,7 @@ func (ai *llmClientOllama) chat(ctx context.Context, messages []Message) <-chan
 			out <- LLMStreamEvent{
 				Type:    LLMStreamEventTypeError,
 				Content: err.Error(),
+				Err:     err,
 			}
 		}
 	}()
@@ -87,3 +99,26 @@ func (ai *llmClientOllama) toOllamaMessages(messages []Message) []api.Message {
 	}
 	return ollamaMessages
 }
+
+func (ai *llmClientOllama) toOllamaOptions() map[string]any {
+	options := map[string]any{}
+	if ai.generation.Temperature != nil {
+		options["temperature"] = *ai.generation.Temperature
+	}
+	if ai.generation.TopP != nil {
+		options["top_p"] = *ai.generation.TopP
+	}
+	if ai.generation.MaxTokens > 0 {
+		options["num_predict"] = ai.generation.MaxTokens
+	}
+	if ai.generation.Seed != nil {
+		options["seed"] = *ai.generation.Seed
+	}
+	if len(ai.generation.Stop) > 0 {
+		options["stop"] = ai.generation.Stop
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
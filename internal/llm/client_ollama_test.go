@@ -56,7 +56,8 @@ func TestSendOllama_Success(t *testing.T) {
 				Message: api.Message{
 					Content: "hello from mock",
 				},
-				Done: true,
+				Done:    true,
+				Metrics: api.Metrics{PromptEvalCount: 12, EvalCount: 4},
 			},
 		},
 	})
@@ -66,6 +67,7 @@ func TestSendOllama_Success(t *testing.T) {
 	res, err := client.Send(t.Context(), messages)
 
 	assert.Equal(t, "hello from mock", res.Content)
+	assert.Equal(t, LLMTokenUsage{InputTokens: 12, OutputTokens: 4}, res.Usage)
 	assert.Nil(t, err)
 
 	client.client.(*ollamaMockClient).AssertExpectations(t)
@@ -100,7 +102,8 @@ func TestStreamOllama_Success(t *testing.T) {
 				Message: api.Message{
 					Content: " from mock",
 				},
-				Done: true,
+				Done:    true,
+				Metrics: api.Metrics{PromptEvalCount: 5, EvalCount: 2},
 			},
 		},
 	})
@@ -118,6 +121,7 @@ func TestStreamOllama_Success(t *testing.T) {
 	assert.Equal(t, " from mock", events[1].Content)
 	assert.Equal(t, LLMStreamEventTypeMessage, events[1].Type)
 	assert.Equal(t, LLMStreamEventTypeComplete, events[2].Type)
+	assert.Equal(t, LLMTokenUsage{InputTokens: 5, OutputTokens: 2}, events[2].Usage)
 
 	client.client.(*ollamaMockClient).AssertExpectations(t)
 }
@@ -159,3 +163,42 @@ func TestToOllamaMessages(t *testing.T) {
 
 	require.Equal(t, expected, result)
 }
+
+func TestToOllamaOptions(t *testing.T) {
+	temperature, topP, seed := 0.0, 0.5, int64(3)
+	testCases := []struct {
+		name       string
+		generation GenerationOptions
+		expected   map[string]any
+	}{
+		{
+			name:       "provider defaults",
+			generation: GenerationOptions{},
+			expected:   nil,
+		},
+		{
+			name: "all options",
+			generation: GenerationOptions{
+				Temperature: &temperature,
+				TopP:        &topP,
+				MaxTokens:   256,
+				Seed:        &seed,
+				Stop:        []string{"END"},
+			},
+			expected: map[string]any{
+				"temperature": 0.0,
+				"top_p":       0.5,
+				"num_predict": int64(256),
+				"seed":        int64(3),
+				"stop":        []string{"END"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ai := &llmClientOllama{generation: tc.generation}
+			assert.Equal(t, tc.expected, ai.toOllamaOptions())
+		})
+	}
+}
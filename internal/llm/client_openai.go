@@ -11,8 +11,9 @@ import (
 type LLMClientOpenAI LLMClient
 
 type llmClientOpenAi struct {
-	client openaiClient
-	model  string
+	client     openaiClient
+	model      string
+	generation GenerationOptions
 }
 
 type openaiClient interface {
@@ -59,15 +60,40 @@ func (c *defaultOpenAiClient) NewStreaming(ctx context.Context, body openai.Chat
 	return &defaultOpenaiChatStream{stream: c.client.Chat.Completions.NewStreaming(ctx, body, opts...)}
 }
 
-func newOpenAIClient(openAIKey string, model string, opts ...option.RequestOption) LLMClientOpenAI {
+func newOpenAIClient(openAIKey string, model string, generation GenerationOptions, opts ...option.RequestOption) LLMClientOpenAI {
 	return &llmClientOpenAi{
 		client: &defaultOpenAiClient{client: openai.NewClient(
 			append([]option.RequestOption{option.WithAPIKey(openAIKey)}, opts...)...,
 		)},
-		model: model,
+		model:      model,
+		generation: generation,
 	}
 }
 
+func (ai *llmClientOpenAi) newParams(messages []Message) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:    ai.model,
+		Messages: ai.toOpenAiMessages(messages),
+		N:        openai.Int(1),
+	}
+	if ai.generation.Temperature != nil {
+		params.Temperature = openai.Float(*ai.generation.Temperature)
+	}
+	if ai.generation.TopP != nil {
+		params.TopP = openai.Float(*ai.generation.TopP)
+	}
+	if ai.generation.MaxTokens > 0 {
+		params.MaxCompletionTokens = openai.Int(ai.generation.MaxTokens)
+	}
+	if ai.generation.Seed != nil {
+		params.Seed = openai.Int(*ai.generation.Seed)
+	}
+	if len(ai.generation.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: ai.generation.Stop}
+	}
+	return params
+}
+
 func (ai *llmClientOpenAi) toOpenAiMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
 	var openAiMessages []openai.ChatCompletionMessageParamUnion
 	for _, msg := range messages {
@@ -86,14 +112,7 @@ func (ai *llmClientOpenAi) toOpenAiMessages(messages []Message) []openai.ChatCom
 }
 
 func (ai *llmClientOpenAi) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
-	res, err := ai.client.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Model:    ai.model,
-			Messages: ai.toOpenAiMessages(messages),
-			N:        openai.Int(1),
-		},
-	)
+	res, err := ai.client.New(ctx, ai.newParams(messages))
 
 	if err != nil {
 		return nil, err
@@ -114,21 +133,20 @@ func (ai *llmClientOpenAi) Stream(ctx context.Context, messages []Message) <-cha
 	go func() {
 		defer close(out)
 		acc := openai.ChatCompletionAccumulator{}
-		aiStream := ai.client.NewStreaming(
-			ctx,
-			openai.ChatCompletionNewParams{
-				Model:    ai.model,
-				Messages: ai.toOpenAiMessages(messages),
-				StreamOptions: openai.ChatCompletionStreamOptionsParam{
-					IncludeUsage: openai.Bool(true),
-				},
-				N: openai.Int(1),
-			},
-		)
+		params := ai.newParams(messages)
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
+		aiStream := ai.client.NewStreaming(ctx, params)
 
 		for aiStream.Next() {
 			chunk := aiStream.Current()
-			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if len(chunk.Choices) == 0 {
+				// the last chunk has no choice and only carries the usage
+				acc.AddChunk(chunk)
+				continue
+			}
+			if chunk.Choices[0].Delta.Content != "" {
 				acc.AddChunk(chunk)
 				out <- LLMStreamEvent{
 					Type:    LLMStreamEventTypeMessage,
@@ -146,13 +164,20 @@ func (ai *llmClientOpenAi) Stream(ctx context.Context, messages []Message) <-cha
 			out <- LLMStreamEvent{
 				Type:    LLMStreamEventTypeError,
 				Content: err.Error(),
+				Err:     err,
 			}
 			return
 		}
 
+		// without any content chunk, like a filtered or stopped answer, the
+		// accumulator holds no choice
+		content := ""
+		if len(acc.ChatCompletion.Choices) > 0 {
+			content = acc.ChatCompletion.Choices[0].Message.Content
+		}
 		out <- LLMStreamEvent{
 			Type:    LLMStreamEventTypeComplete,
-			Content: acc.ChatCompletion.Choices[0].Message.Content,
+			Content: content,
 			Usage: LLMTokenUsage{
 				InputTokens:  acc.ChatCompletion.Usage.PromptTokens,
 				OutputTokens: acc.ChatCompletion.Usage.CompletionTokens,
@@ -220,6 +220,7 @@ func TestSendStream_Success(t *testing.T) {
 }
 
 func TestSendStream_Error(t *testing.T) {
+	streamErr := errors.New("failed to stream content")
 	messages := []Message{
 		{Role: User, Content: "Hello"},
 	}
@@ -251,7 +252,7 @@ func TestSendStream_Error(t *testing.T) {
 	}).Once()
 	mockStream.On("Next").Return(false).Once()
 
-	mockStream.On("Err").Return(errors.New("failed to stream content"))
+	mockStream.On("Err").Return(streamErr)
 
 	stream := mockClient.Stream(t.Context(), messages)
 
@@ -277,6 +278,7 @@ func TestSendStream_Error(t *testing.T) {
 	assert.Equal(t, LLMStreamEvent{
 		Type:    LLMStreamEventTypeError,
 		Content: "failed to stream content",
+		Err:     streamErr,
 	}, events[1])
 
 }
@@ -312,7 +314,7 @@ func TestSendStream_HttpSuccess(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client := newOpenAIClient("", "model", option.WithHTTPClient(ts.Client()), option.WithBaseURL(ts.URL))
+	client := newOpenAIClient("", "model", GenerationOptions{}, option.WithHTTPClient(ts.Client()), option.WithBaseURL(ts.URL))
 
 	stream := client.Stream(t.Context(), messages)
 
@@ -336,3 +338,91 @@ func TestSendStream_HttpSuccess(t *testing.T) {
 	}, events[0])
 
 }
+
+func TestSendStream_HttpUsageChunk(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// the usage comes last, in a chunk without choices
+		w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[{"delta":{"content":"hello"},"finish_reason":"stop"}],"usage":null}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":1,"total_tokens":10}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer ts.Close()
+
+	client := newOpenAIClient("", "model", GenerationOptions{}, option.WithHTTPClient(ts.Client()), option.WithBaseURL(ts.URL))
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, LLMStreamEvent{
+		Type:    LLMStreamEventTypeComplete,
+		Content: "hello",
+		Usage: LLMTokenUsage{
+			InputTokens:  9,
+			OutputTokens: 1,
+		},
+	}, events[1])
+}
+
+func TestSendStream_HttpWithoutContent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[{"delta":{"role":"assistant"},"finish_reason":null}],"usage":null}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[{"delta":{},"finish_reason":"content_filter"}],"usage":null}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":0,"total_tokens":9}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer ts.Close()
+
+	client := newOpenAIClient("", "model", GenerationOptions{}, option.WithHTTPClient(ts.Client()), option.WithBaseURL(ts.URL))
+
+	var events []LLMStreamEvent
+	for event := range client.Stream(t.Context(), []Message{{Role: User, Content: "Hello"}}) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 1)
+	assert.Equal(t, LLMStreamEvent{
+		Type:  LLMStreamEventTypeComplete,
+		Usage: LLMTokenUsage{InputTokens: 9},
+	}, events[0])
+}
+
+func TestSendOpenai_WithGenerationOptions(t *testing.T) {
+	temperature, topP, seed := 0.2, 0.9, int64(7)
+	mockClient := newOpenaiMockClient("openai-model")
+	mockClient.generation = GenerationOptions{
+		Temperature: &temperature,
+		TopP:        &topP,
+		MaxTokens:   512,
+		Seed:        &seed,
+		Stop:        []string{"END"},
+	}
+	mockClient.client.(*openaiMockClient).
+		On("New", t.Context(), openai.ChatCompletionNewParams{
+			Model: "openai-model",
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.UserMessage("Hello"),
+			},
+			N:                   openai.Int(1),
+			Temperature:         openai.Float(0.2),
+			TopP:                openai.Float(0.9),
+			MaxCompletionTokens: openai.Int(512),
+			Seed:                openai.Int(7),
+			Stop:                openai.ChatCompletionNewParamsStopUnion{OfStringArray: []string{"END"}},
+		}, mock.Anything).
+		Return(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: "ok"}},
+			},
+		}, nil)
+
+	res, err := mockClient.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Content)
+	mockClient.client.(*openaiMockClient).AssertExpectations(t)
+}
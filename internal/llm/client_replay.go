@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ReplayOptions serves the answers of a transcript recorded with
+// NewRecordingClient, used by the replay provider.
+type ReplayOptions struct {
+	File string
+}
+
+type llmClientReplay struct {
+	file       string
+	transcript *Transcript
+	mu         sync.Mutex
+	used       []bool
+}
+
+func newReplayClient(opts ReplayOptions) (LLMClient, error) {
+	if opts.File == "" {
+		return nil, fmt.Errorf("replay file is not set")
+	}
+	transcript, err := LoadTranscript(opts.File, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replay file: %v", err)
+	}
+	return &llmClientReplay{file: opts.File, transcript: transcript, used: make([]bool, len(transcript.Interactions))}, nil
+}
+
+// interaction returns the first recorded answer to messages not served yet,
+// so a request recorded failing then succeeding replays both, or the last
+// one once they were all served.
+func (c *llmClientReplay) interaction(messages []Message) (TranscriptInteraction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	request := newTranscriptMessages(messages)
+	last := -1
+	for i, interaction := range c.transcript.Interactions {
+		if !slices.Equal(interaction.Messages, request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction, nil
+		}
+		last = i
+	}
+	if last >= 0 {
+		return c.transcript.Interactions[last], nil
+	}
+
+	lastMessage := ""
+	if len(messages) > 0 {
+		lastMessage = messages[len(messages)-1].Content
+		if len(lastMessage) > 80 {
+			lastMessage = lastMessage[:80] + "..."
+		}
+	}
+	return TranscriptInteraction{}, fmt.Errorf("no recorded answer in %s to the request of %d messages ending with %q", c.file, len(messages), lastMessage)
+}
+
+func (c *llmClientReplay) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	interaction, err := c.interaction(messages)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	return &LLMSendResponse{Content: interaction.Content, Usage: LLMTokenUsage(interaction.Usage)}, nil
+}
+
+// Stream sends the recorded chunks, or the whole answer as a single chunk
+// when it was not streamed.
+func (c *llmClientReplay) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		interaction, err := c.interaction(messages)
+		if err != nil {
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+			return
+		}
+
+		chunks := interaction.Chunks
+		if chunks == nil && interaction.Content != "" {
+			chunks = []string{interaction.Content}
+		}
+		for _, chunk := range chunks {
+			if err := ctx.Err(); err != nil {
+				out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+				return
+			}
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: chunk}
+		}
+		if interaction.Error != "" {
+			out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: interaction.Error, Err: errors.New(interaction.Error)}
+			return
+		}
+		out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: interaction.Content, Usage: LLMTokenUsage(interaction.Usage)}
+	}()
+	return out
+}
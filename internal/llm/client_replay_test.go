@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReplayClient(t *testing.T, interactions ...TranscriptInteraction) LLMClient {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	require.NoError(t, (&Transcript{Interactions: interactions}).Save(path))
+	client, err := NewClient(LLMProviderReplay, LLMClientOptions{Replay: ReplayOptions{File: path}})
+	require.NoError(t, err)
+	return client
+}
+
+var replayRequest = []TranscriptMessage{{Role: System, Content: "prompt"}, {Role: User, Content: "diff"}}
+
+func TestReplayClient_Send(t *testing.T) {
+	client := newTestReplayClient(t,
+		TranscriptInteraction{Messages: []TranscriptMessage{{Role: User, Content: "other"}}, Content: "other review"},
+		TranscriptInteraction{Messages: replayRequest, Error: "server overloaded"},
+		TranscriptInteraction{Messages: replayRequest, Content: "review", Usage: TranscriptUsage{InputTokens: 10, OutputTokens: 2}},
+	)
+	messages := []Message{{Role: System, Content: "prompt", Hidden: true}, {Role: User, Content: "diff", Hidden: true}}
+
+	_, err := client.Send(t.Context(), messages)
+	assert.EqualError(t, err, "server overloaded")
+
+	expected := &LLMSendResponse{Content: "review", Usage: LLMTokenUsage{InputTokens: 10, OutputTokens: 2}}
+	res, err := client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res)
+
+	res, err = client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res)
+}
+
+func TestReplayClient_SendUnmatched(t *testing.T) {
+	client := newTestReplayClient(t, TranscriptInteraction{Messages: replayRequest, Content: "review"})
+
+	_, err := client.Send(t.Context(), []Message{{Role: System, Content: "prompt"}, {Role: User, Content: "another diff"}})
+
+	assert.ErrorContains(t, err, `no recorded answer in `)
+	assert.ErrorContains(t, err, `to the request of 2 messages ending with "another diff"`)
+}
+
+func TestReplayClient_Stream(t *testing.T) {
+	client := newTestReplayClient(t,
+		TranscriptInteraction{Messages: replayRequest, Content: "hello", Chunks: []string{"hel", "lo"}, Usage: TranscriptUsage{OutputTokens: 1}},
+		TranscriptInteraction{Messages: []TranscriptMessage{{Role: User, Content: "sent"}}, Content: "whole answer"},
+		TranscriptInteraction{Messages: []TranscriptMessage{{Role: User, Content: "failed"}}, Content: "part", Chunks: []string{"part"}, Error: "connection reset"},
+	)
+
+	events := collectStreamEvents(client.Stream(t.Context(), []Message{{Role: System, Content: "prompt"}, {Role: User, Content: "diff"}}))
+	assert.Equal(t, []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "hel"},
+		{Type: LLMStreamEventTypeMessage, Content: "lo"},
+		{Type: LLMStreamEventTypeComplete, Content: "hello", Usage: LLMTokenUsage{OutputTokens: 1}},
+	}, events)
+
+	events = collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "sent"}}))
+	assert.Equal(t, []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "whole answer"},
+		{Type: LLMStreamEventTypeComplete, Content: "whole answer"},
+	}, events)
+
+	events = collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "failed"}}))
+	require.Len(t, events, 2)
+	assert.Equal(t, LLMStreamEventTypeError, events[1].Type)
+	assert.Equal(t, "connection reset", events[1].Content)
+
+	events = collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "unknown"}}))
+	require.Len(t, events, 1)
+	assert.Equal(t, LLMStreamEventTypeError, events[0].Type)
+	assert.Contains(t, events[0].Content, "no recorded answer")
+}
+
+func TestReplayClient_StreamCancelled(t *testing.T) {
+	client := newTestReplayClient(t, TranscriptInteraction{Messages: replayRequest, Content: "hello", Chunks: []string{"hel", "lo"}})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	events := collectStreamEvents(client.Stream(ctx, []Message{{Role: System, Content: "prompt"}, {Role: User, Content: "diff"}}))
+
+	assert.Equal(t, LLMStreamEventTypeError, events[len(events)-1].Type)
+	assert.ErrorIs(t, events[len(events)-1].Err, context.Canceled)
+}
+
+func TestNewClient_Replay_MissingFile(t *testing.T) {
+	client, err := NewClient(LLMProviderReplay, LLMClientOptions{})
+	assert.Nil(t, client)
+	assert.EqualError(t, err, "replay file is not set")
+
+	client, err = NewClient(LLMProviderReplay, LLMClientOptions{Replay: ReplayOptions{File: filepath.Join(t.TempDir(), "missing.json")}})
+	assert.Nil(t, client)
+	assert.ErrorContains(t, err, "failed to load replay file")
+}
+
+func TestNewClient_Replay_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o600))
+
+	_, err := NewClient(LLMProviderReplay, LLMClientOptions{Replay: ReplayOptions{File: path}})
+
+	assert.ErrorContains(t, err, "failed to load replay file: invalid transcript")
+}
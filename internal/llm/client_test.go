@@ -1,8 +1,11 @@
 package llm
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/openai/openai-go/option"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,3 +62,113 @@ func TestNewClient_InvalidProvider(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, "unknown: invalid provider", err.Error())
 }
+
+func TestNewClient_Anthropic_MissingAPIKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	client, err := NewClient(LLMProviderAnthropic, LLMClientOptions{Model: "claude-sonnet-4-0"})
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.Equal(t, "ANTHROPIC_API_KEY environment variable is not set", err.Error())
+}
+
+func TestNewClient_Anthropic_WithAPIKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "dummy-key")
+
+	client, err := NewClient(LLMProviderAnthropic, LLMClientOptions{Model: "claude-sonnet-4-0"})
+	require.NoError(t, err)
+	assert.IsType(t, &llmClientAnthropic{}, client)
+	assert.Equal(t, client.(*llmClientAnthropic).model, "claude-sonnet-4-0")
+}
+
+func TestNewClient_OpenAI_WithBaseURLAndNoAPIKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+
+	client, err := NewClient(LLMProviderOpenAI, LLMClientOptions{
+		Model:  "local-model",
+		OpenAI: OpenAIOptions{BaseURL: "http://localhost:8000/v1"},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &llmClientOpenAi{}, client)
+}
+
+func TestOpenAIOptions_RequestOptions(t *testing.T) {
+	var received *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	defer ts.Close()
+
+	opts := OpenAIOptions{
+		BaseURL:      ts.URL + "/gateway/v1",
+		Organization: "org-1",
+		Project:      "proj-1",
+		Headers:      map[string]string{"X-Team": "diffai", "X-Trace": "abc"},
+	}
+	client := newOpenAIClient("key", "model", GenerationOptions{}, append(opts.requestOptions(), option.WithHTTPClient(ts.Client()))...)
+
+	res, err := client.Send(t.Context(), []Message{{Role: User, Content: "Hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Content)
+
+	require.NotNil(t, received)
+	assert.Equal(t, "/gateway/v1/chat/completions", received.URL.Path)
+	assert.Equal(t, "org-1", received.Header.Get("OpenAI-Organization"))
+	assert.Equal(t, "proj-1", received.Header.Get("OpenAI-Project"))
+	assert.Equal(t, "diffai", received.Header.Get("X-Team"))
+	assert.Equal(t, "abc", received.Header.Get("X-Trace"))
+}
+
+func TestNewClient_Gemini_MissingAPIKey(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+
+	client, err := NewClient(LLMProviderGemini, LLMClientOptions{Model: "gemini-2.5-pro"})
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.Equal(t, "GEMINI_API_KEY environment variable is not set", err.Error())
+}
+
+func TestNewClient_Gemini_WithAPIKey(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "dummy-key")
+
+	client, err := NewClient(LLMProviderGemini, LLMClientOptions{Model: "gemini-2.5-pro"})
+	require.NoError(t, err)
+	assert.IsType(t, &llmClientGemini{}, client)
+	assert.Equal(t, client.(*llmClientGemini).model, "gemini-2.5-pro")
+}
+
+func TestNewClient_Azure_MissingEndpoint(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "dummy-key")
+
+	client, err := NewClient(LLMProviderAzure, LLMClientOptions{Model: "gpt-4o"})
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.Equal(t, "azure openai endpoint is not set", err.Error())
+}
+
+func TestNewClient_Azure_MissingCredentials(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	t.Setenv("AZURE_OPENAI_AD_TOKEN", "")
+
+	client, err := NewClient(LLMProviderAzure, LLMClientOptions{
+		Model: "gpt-4o",
+		Azure: AzureOpenAIOptions{Endpoint: "https://example.openai.azure.com"},
+	})
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.Equal(t, "AZURE_OPENAI_API_KEY or AZURE_OPENAI_AD_TOKEN environment variable is not set", err.Error())
+}
+
+func TestNewClient_Azure_DeploymentDefaultsToModel(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "dummy-key")
+
+	client, err := NewClient(LLMProviderAzure, LLMClientOptions{
+		Model: "gpt-4o",
+		Azure: AzureOpenAIOptions{Endpoint: "https://example.openai.azure.com"},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &llmClientOpenAi{}, client)
+	assert.Equal(t, "gpt-4o", client.(*llmClientOpenAi).model)
+}
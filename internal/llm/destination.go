@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"cmp"
+	"os"
+	"strings"
+)
+
+const (
+	OPENAI_DEFAULT_BASE_URL = "https://api.openai.com/v1/"
+	REDACTED_HEADER         = "[redacted]"
+)
+
+// secretHeaderParts are the parts of header names holding credentials.
+var secretHeaderParts = []string{"authorization", "key", "token", "secret", "cookie", "password"}
+
+// Destination is where NewClient sends the requests of a provider. Endpoint
+// is empty when no request leaves the machine, or when the provider can't
+// tell yet. Headers are the ones set from the options and the environment on
+// top of the credentials, secret values redacted.
+type Destination struct {
+	Endpoint string
+	Headers  map[string]string
+}
+
+// NewDestination resolves the destination of the requests NewClient would
+// send to provider with opts, without creating the client.
+func NewDestination(provider LLMProvider, opts LLMClientOptions) Destination {
+	switch provider {
+	case LLMProviderOpenAI:
+		destination := Destination{
+			Endpoint: cmp.Or(opts.OpenAI.BaseURL, os.Getenv("OPENAI_BASE_URL"), OPENAI_DEFAULT_BASE_URL),
+			Headers:  map[string]string{},
+		}
+		if organization := cmp.Or(opts.OpenAI.Organization, os.Getenv("OPENAI_ORG_ID")); organization != "" {
+			destination.Headers["OpenAI-Organization"] = organization
+		}
+		if project := cmp.Or(opts.OpenAI.Project, os.Getenv("OPENAI_PROJECT_ID")); project != "" {
+			destination.Headers["OpenAI-Project"] = project
+		}
+		for key, value := range opts.OpenAI.Headers {
+			destination.Headers[key] = redactHeader(key, value)
+		}
+		return destination
+	case LLMProviderOllama:
+		return Destination{Endpoint: os.Getenv("OLLAMA_ENDPOINT")}
+	case LLMProviderAnthropic:
+		return Destination{Endpoint: ANTHROPIC_DEFAULT_BASE_URL}
+	case LLMProviderGemini:
+		return Destination{Endpoint: GEMINI_DEFAULT_BASE_URL}
+	case LLMProviderAzure:
+		endpoint, err := azureOpenAIDeploymentURL(opts.Azure.Endpoint, cmp.Or(opts.Azure.Deployment, opts.Model))
+		if err != nil {
+			return Destination{Endpoint: opts.Azure.Endpoint}
+		}
+		return Destination{Endpoint: endpoint + "?api-version=" + cmp.Or(opts.Azure.APIVersion, AZURE_OPENAI_DEFAULT_API_VERSION)}
+	default:
+		return Destination{}
+	}
+}
+
+func redactHeader(key string, value string) string {
+	key = strings.ToLower(key)
+	for _, part := range secretHeaderParts {
+		if strings.Contains(key, part) {
+			return REDACTED_HEADER
+		}
+	}
+	return value
+}
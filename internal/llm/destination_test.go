@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDestination(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_ORG_ID", "")
+	t.Setenv("OPENAI_PROJECT_ID", "")
+	t.Setenv("OLLAMA_ENDPOINT", "http://localhost:11434")
+
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		opts     LLMClientOptions
+		expected Destination
+	}{
+		{
+			name:     "openai default",
+			provider: LLMProviderOpenAI,
+			expected: Destination{Endpoint: OPENAI_DEFAULT_BASE_URL, Headers: map[string]string{}},
+		},
+		{
+			name:     "openai compatible",
+			provider: LLMProviderOpenAI,
+			opts: LLMClientOptions{OpenAI: OpenAIOptions{
+				BaseURL:      "https://gateway.internal/v1",
+				Organization: "org",
+				Project:      "proj",
+				Headers:      map[string]string{"X-Tenant": "team", "Authorization": "Bearer secret", "X-Api-Key": "secret"},
+			}},
+			expected: Destination{Endpoint: "https://gateway.internal/v1", Headers: map[string]string{
+				"OpenAI-Organization": "org",
+				"OpenAI-Project":      "proj",
+				"X-Tenant":            "team",
+				"Authorization":       REDACTED_HEADER,
+				"X-Api-Key":           REDACTED_HEADER,
+			}},
+		},
+		{
+			name:     "ollama",
+			provider: LLMProviderOllama,
+			expected: Destination{Endpoint: "http://localhost:11434"},
+		},
+		{
+			name:     "azure deployment of the model",
+			provider: LLMProviderAzure,
+			opts:     LLMClientOptions{Model: "gpt-4o", Azure: AzureOpenAIOptions{Endpoint: "https://example.openai.azure.com/"}},
+			expected: Destination{Endpoint: "https://example.openai.azure.com/openai/deployments/gpt-4o/?api-version=" + AZURE_OPENAI_DEFAULT_API_VERSION},
+		},
+		{
+			name:     "azure deployment",
+			provider: LLMProviderAzure,
+			opts:     LLMClientOptions{Model: "gpt-4o", Azure: AzureOpenAIOptions{Endpoint: "https://example.openai.azure.com", Deployment: "prod", APIVersion: "2025-01-01"}},
+			expected: Destination{Endpoint: "https://example.openai.azure.com/openai/deployments/prod/?api-version=2025-01-01"},
+		},
+		{
+			name:     "mock",
+			provider: LLMProviderMock,
+			expected: Destination{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewDestination(tt.provider, tt.opts))
+		})
+	}
+}
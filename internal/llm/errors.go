@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+)
+
+type LLMErrorKind string
+
+const (
+	LLMErrorRateLimit      LLMErrorKind = "rate limit"
+	LLMErrorTimeout        LLMErrorKind = "timeout"
+	LLMErrorServer         LLMErrorKind = "server error"
+	LLMErrorConnection     LLMErrorKind = "connection error"
+	LLMErrorAuth           LLMErrorKind = "authentication error"
+	LLMErrorInvalidRequest LLMErrorKind = "invalid request"
+	LLMErrorCancelled      LLMErrorKind = "cancelled"
+	LLMErrorUnknown        LLMErrorKind = "unknown error"
+)
+
+// Retryable reports whether the same request may succeed later.
+func (k LLMErrorKind) Retryable() bool {
+	switch k {
+	case LLMErrorRateLimit, LLMErrorTimeout, LLMErrorServer, LLMErrorConnection:
+		return true
+	default:
+		return false
+	}
+}
+
+// LLMAPIError is an error answered by a provider API. StatusCode is 0 for
+// errors sent in the middle of a stream, Type then tells what happened.
+type LLMAPIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LLMAPIError) Error() string {
+	return e.Message
+}
+
+// ClassifyError tells why a request failed, from the errors of the provider
+// SDKs, of LLMAPIError and of the network.
+func ClassifyError(err error) LLMErrorKind {
+	if err == nil {
+		return LLMErrorUnknown
+	}
+
+	var apiErr *LLMAPIError
+	var openaiErr *openai.Error
+	var ollamaErr api.StatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return LLMErrorCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return LLMErrorTimeout
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode == 0 {
+			return classifyErrorType(apiErr.Type)
+		}
+		return classifyStatus(apiErr.StatusCode)
+	case errors.As(err, &openaiErr):
+		return classifyStatus(openaiErr.StatusCode)
+	case errors.As(err, &ollamaErr):
+		return classifyStatus(ollamaErr.StatusCode)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return LLMErrorTimeout
+	case errors.As(err, new(*net.OpError)),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return LLMErrorConnection
+	default:
+		return LLMErrorUnknown
+	}
+}
+
+func classifyStatus(status int) LLMErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return LLMErrorRateLimit
+	case status == http.StatusRequestTimeout:
+		return LLMErrorTimeout
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return LLMErrorAuth
+	case status >= 500:
+		return LLMErrorServer
+	case status >= 400:
+		return LLMErrorInvalidRequest
+	default:
+		return LLMErrorUnknown
+	}
+}
+
+// classifyErrorType maps the error types providers send in a stream.
+func classifyErrorType(errorType string) LLMErrorKind {
+	switch errorType {
+	case "rate_limit_error":
+		return LLMErrorRateLimit
+	case "overloaded_error", "api_error":
+		return LLMErrorServer
+	case "authentication_error", "permission_error":
+		return LLMErrorAuth
+	case "invalid_request_error", "not_found_error", "request_too_large":
+		return LLMErrorInvalidRequest
+	default:
+		return LLMErrorUnknown
+	}
+}
+
+// RetryAfter returns the delay asked by the provider before retrying, 0 when
+// it didn't ask for one.
+func RetryAfter(err error) time.Duration {
+	var apiErr *LLMAPIError
+	var openaiErr *openai.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.RetryAfter
+	case errors.As(err, &openaiErr) && openaiErr.Response != nil:
+		return parseRetryAfter(openaiErr.Response.Header)
+	default:
+		return 0
+	}
+}
+
+// parseRetryAfter reads retry-after-ms, sent by OpenAI, then Retry-After in
+// seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected LLMErrorKind
+	}{
+		{name: "rate limit", err: &LLMAPIError{StatusCode: 429}, expected: LLMErrorRateLimit},
+		{name: "server error", err: &openai.Error{StatusCode: 503}, expected: LLMErrorServer},
+		{name: "overloaded anthropic", err: &LLMAPIError{StatusCode: 529}, expected: LLMErrorServer},
+		{name: "request timeout", err: &LLMAPIError{StatusCode: 408}, expected: LLMErrorTimeout},
+		{name: "auth", err: &openai.Error{StatusCode: 401}, expected: LLMErrorAuth},
+		{name: "invalid request", err: api.StatusError{StatusCode: 400}, expected: LLMErrorInvalidRequest},
+		{name: "stream error type", err: &LLMAPIError{Type: "overloaded_error"}, expected: LLMErrorServer},
+		{name: "wrapped", err: fmt.Errorf("ollama error: %w", api.StatusError{StatusCode: 502}), expected: LLMErrorServer},
+		{name: "network timeout", err: &net.OpError{Op: "read", Err: timeoutError{}}, expected: LLMErrorTimeout},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, expected: LLMErrorConnection},
+		{name: "dropped connection", err: io.ErrUnexpectedEOF, expected: LLMErrorConnection},
+		{name: "cancelled", err: context.Canceled, expected: LLMErrorCancelled},
+		{name: "unknown", err: errors.New("boom"), expected: LLMErrorUnknown},
+		{name: "nil", err: nil, expected: LLMErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	openaiErr := func(header http.Header) error {
+		return &openai.Error{StatusCode: 429, Response: &http.Response{Header: header}}
+	}
+
+	assert.Equal(t, 2*time.Second, RetryAfter(openaiErr(http.Header{"Retry-After": {"2"}})))
+	assert.Equal(t, 250*time.Millisecond, RetryAfter(openaiErr(http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}})))
+	assert.InDelta(t, 10*time.Second, RetryAfter(openaiErr(http.Header{"Retry-After": {time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}})), float64(time.Second))
+	assert.Equal(t, 3*time.Second, RetryAfter(&LLMAPIError{StatusCode: 429, RetryAfter: 3 * time.Second}))
+	assert.Zero(t, RetryAfter(openaiErr(http.Header{})))
+	assert.Zero(t, RetryAfter(errors.New("boom")))
+}
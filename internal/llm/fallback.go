@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// FallbackTarget is one provider and model of a fallback chain.
+type FallbackTarget struct {
+	Provider LLMProvider
+	Model    string
+	Client   LLMClient
+}
+
+func (t FallbackTarget) String() string {
+	return fmt.Sprintf("%s:%s", t.Provider, t.Model)
+}
+
+type FallbackOptions struct {
+	// OnFallback is called when target failed with err and next takes over.
+	OnFallback func(target FallbackTarget, err error, next FallbackTarget)
+}
+
+type fallbackClient struct {
+	targets []FallbackTarget
+	opts    FallbackOptions
+}
+
+// NewFallbackClient sends each request to the first target, then to the
+// next ones while they fail. Targets retry transient errors on their own,
+// see NewRetryingClient. A cancelled request is not passed on, nor a stream
+// failing after it sent content. Responses and stream events tell which
+// target answered in AnsweredBy.
+func NewFallbackClient(targets []FallbackTarget, opts FallbackOptions) LLMClient {
+	return &fallbackClient{targets: targets, opts: opts}
+}
+
+func (c *fallbackClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	for i, target := range c.targets {
+		res, err := target.Client.Send(ctx, messages)
+		if err == nil {
+			res.AnsweredBy = target.String()
+			return res, nil
+		}
+		if !c.fallback(ctx, i, err) {
+			return res, err
+		}
+	}
+	return nil, fmt.Errorf("no model to send the request to")
+}
+
+func (c *fallbackClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		for i, target := range c.targets {
+			var failure *LLMStreamEvent
+			started := false
+			for event := range target.Client.Stream(ctx, messages) {
+				if event.Type == LLMStreamEventTypeError && !started {
+					failure = &event
+					continue
+				}
+				started = started || event.Type == LLMStreamEventTypeMessage
+				event.AnsweredBy = target.String()
+				out <- event
+			}
+			if failure == nil {
+				return
+			}
+			if !c.fallback(ctx, i, failure.Err) {
+				failure.AnsweredBy = target.String()
+				out <- *failure
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// fallback reports whether the target following the i-th one should take
+// over its failed request.
+func (c *fallbackClient) fallback(ctx context.Context, i int, err error) bool {
+	if i+1 >= len(c.targets) || ctx.Err() != nil {
+		return false
+	}
+	if c.opts.OnFallback != nil {
+		c.opts.OnFallback(c.targets[i], err, c.targets[i+1])
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fallbackTargets(clients ...*retryTestClient) []FallbackTarget {
+	targets := []FallbackTarget{
+		{Provider: LLMProviderOpenAI, Model: "gpt-4.1"},
+		{Provider: LLMProviderOllama, Model: "qwen2.5-coder:7b"},
+	}
+	for i, client := range clients {
+		targets[i].Client = client
+	}
+	return targets[:len(clients)]
+}
+
+func TestFallbackClient_Send(t *testing.T) {
+	offline := errors.New("dial tcp: no route to host")
+	primary := &retryTestClient{sends: []error{offline}}
+	secondary := &retryTestClient{sends: []error{nil}}
+	var fallbacks []string
+	client := NewFallbackClient(fallbackTargets(primary, secondary), FallbackOptions{
+		OnFallback: func(target FallbackTarget, err error, next FallbackTarget) {
+			assert.Equal(t, offline, err)
+			fallbacks = append(fallbacks, target.String()+" -> "+next.String())
+		},
+	})
+
+	res, err := client.Send(t.Context(), nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, &LLMSendResponse{Content: "review", AnsweredBy: "ollama:qwen2.5-coder:7b"}, res)
+	assert.Equal(t, []string{"openai:gpt-4.1 -> ollama:qwen2.5-coder:7b"}, fallbacks)
+}
+
+func TestFallbackClient_SendPrimaryAnswers(t *testing.T) {
+	secondary := &retryTestClient{}
+	client := NewFallbackClient(fallbackTargets(&retryTestClient{sends: []error{nil}}, secondary), FallbackOptions{})
+
+	res, err := client.Send(t.Context(), nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "openai:gpt-4.1", res.AnsweredBy)
+	assert.Zero(t, secondary.calls)
+}
+
+func TestFallbackClient_SendAllFail(t *testing.T) {
+	last := errors.New("connection refused")
+	client := NewFallbackClient(fallbackTargets(
+		&retryTestClient{sends: []error{errors.New("offline")}},
+		&retryTestClient{sends: []error{last}},
+	), FallbackOptions{})
+
+	_, err := client.Send(t.Context(), nil)
+
+	assert.Equal(t, last, err)
+}
+
+func TestFallbackClient_SendCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	secondary := &retryTestClient{}
+	client := NewFallbackClient(fallbackTargets(&retryTestClient{sends: []error{context.Canceled}}, secondary), FallbackOptions{})
+
+	_, err := client.Send(ctx, nil)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Zero(t, secondary.calls)
+}
+
+func TestFallbackClient_Stream(t *testing.T) {
+	failed := LLMStreamEvent{Type: LLMStreamEventTypeError, Content: "offline", Err: errors.New("offline")}
+	content := LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "hello"}
+	complete := LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: "hello"}
+	answeredBy := func(event LLMStreamEvent, target string) LLMStreamEvent {
+		event.AnsweredBy = target
+		return event
+	}
+
+	tests := []struct {
+		name           string
+		primary        []LLMStreamEvent
+		secondary      [][]LLMStreamEvent
+		expectedEvents []LLMStreamEvent
+	}{
+		{
+			name:      "fallback before content",
+			primary:   []LLMStreamEvent{failed},
+			secondary: [][]LLMStreamEvent{{content, complete}},
+			expectedEvents: []LLMStreamEvent{
+				answeredBy(content, "ollama:qwen2.5-coder:7b"),
+				answeredBy(complete, "ollama:qwen2.5-coder:7b"),
+			},
+		},
+		{
+			name:    "no fallback after content",
+			primary: []LLMStreamEvent{content, failed},
+			expectedEvents: []LLMStreamEvent{
+				answeredBy(content, "openai:gpt-4.1"),
+				answeredBy(failed, "openai:gpt-4.1"),
+			},
+		},
+		{
+			name:           "all fail",
+			primary:        []LLMStreamEvent{failed},
+			secondary:      [][]LLMStreamEvent{{failed}},
+			expectedEvents: []LLMStreamEvent{answeredBy(failed, "ollama:qwen2.5-coder:7b")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewFallbackClient(fallbackTargets(
+				&retryTestClient{streams: [][]LLMStreamEvent{tt.primary}},
+				&retryTestClient{streams: tt.secondary},
+			), FallbackOptions{})
+
+			var events []LLMStreamEvent
+			for event := range client.Stream(t.Context(), nil) {
+				events = append(events, event)
+			}
+
+			assert.Equal(t, tt.expectedEvents, events)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+type featuresClient struct {
+	client    LLMClient
+	model     ModelInfo
+	tokenizer Tokenizer
+}
+
+// NewFeaturesClient adapts the requests of client to the features model
+// lacks. Without system prompt support, the system messages are folded into
+// the first user message. Without streaming, a stream is answered by a
+// single request sent as one chunk. Without usage reporting, the usage is
+// estimated with tokenizer.
+func NewFeaturesClient(client LLMClient, model ModelInfo, tokenizer Tokenizer) LLMClient {
+	if model.SystemPrompt && model.Streaming && model.UsageReporting {
+		return client
+	}
+	return &featuresClient{client: client, model: model, tokenizer: tokenizer}
+}
+
+func (c *featuresClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	messages = c.adapt(messages)
+	res, err := c.client.Send(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	if !c.model.UsageReporting {
+		res.Usage = c.estimateUsage(messages, res.Content)
+	}
+	return res, nil
+}
+
+func (c *featuresClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	messages = c.adapt(messages)
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		if !c.model.Streaming {
+			c.sendAsStream(ctx, messages, out)
+			return
+		}
+
+		var content strings.Builder
+		for event := range c.client.Stream(ctx, messages) {
+			switch event.Type {
+			case LLMStreamEventTypeMessage:
+				content.WriteString(event.Content)
+			case LLMStreamEventTypeComplete:
+				if !c.model.UsageReporting {
+					event.Usage = c.estimateUsage(messages, content.String())
+				}
+			}
+			out <- event
+		}
+	}()
+	return out
+}
+
+func (c *featuresClient) sendAsStream(ctx context.Context, messages []Message, out chan<- LLMStreamEvent) {
+	res, err := c.client.Send(ctx, messages)
+	if err != nil {
+		out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+		return
+	}
+	if !c.model.UsageReporting {
+		res.Usage = c.estimateUsage(messages, res.Content)
+	}
+	if res.Content != "" {
+		out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: res.Content, AnsweredBy: res.AnsweredBy, Cached: res.Cached}
+	}
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: res.Content, Usage: res.Usage, AnsweredBy: res.AnsweredBy, Cached: res.Cached}
+}
+
+// adapt folds the system messages into the first user message when the
+// model has no system prompt.
+func (c *featuresClient) adapt(messages []Message) []Message {
+	if c.model.SystemPrompt {
+		return messages
+	}
+
+	var system []string
+	var result []Message
+	for _, message := range messages {
+		if message.Role == System {
+			system = append(system, message.Content)
+			continue
+		}
+		result = append(result, message)
+	}
+	if len(system) == 0 {
+		return messages
+	}
+
+	instructions := strings.Join(system, "\n\n")
+	for i := range result {
+		if result[i].Role == User {
+			result[i].Content = instructions + "\n\n" + result[i].Content
+			return result
+		}
+	}
+	return append([]Message{{Role: User, Content: instructions, Hidden: true}}, result...)
+}
+
+func (c *featuresClient) estimateUsage(messages []Message, answer string) LLMTokenUsage {
+	usage := LLMTokenUsage{OutputTokens: int64(c.tokenizer.CountTokens(answer))}
+	for _, message := range messages {
+		usage.InputTokens += int64(c.tokenizer.CountTokens(message.Content))
+	}
+	return usage
+}
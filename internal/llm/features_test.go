@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// featuresTestClient answers "review" without usage, and records the
+// messages and the method of the last request.
+type featuresTestClient struct {
+	messages []Message
+	method   string
+}
+
+func (c *featuresTestClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	c.messages, c.method = messages, "Send"
+	return &LLMSendResponse{Content: "review"}, nil
+}
+
+func (c *featuresTestClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	c.messages, c.method = messages, "Stream"
+	out := make(chan LLMStreamEvent, 3)
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "rev"}
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "iew"}
+	out <- LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: "review"}
+	close(out)
+	return out
+}
+
+func TestNewFeaturesClient_AllFeatures(t *testing.T) {
+	inner := &featuresTestClient{}
+	model := chatModel(LLMProviderOpenAI, "gpt-4.1", 1_047_576, 32_768, 2, 8)
+
+	assert.Same(t, inner, NewFeaturesClient(inner, model, HeuristicTokenizer{}))
+}
+
+func TestFeaturesClient_WithoutSystemPrompt(t *testing.T) {
+	inner := &featuresTestClient{}
+	model := chatModel(LLMProviderOllama, "tiny", 32_768, 8_192, 0, 0)
+	model.SystemPrompt = false
+	client := NewFeaturesClient(inner, model, HeuristicTokenizer{})
+	messages := []Message{
+		{Role: System, Content: "prompt", Hidden: true},
+		{Role: User, Content: "diff", Hidden: true},
+		{Role: Assistant, Content: "review"},
+		{Role: User, Content: "why?"},
+	}
+
+	_, err := client.Send(t.Context(), messages)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Message{
+		{Role: User, Content: "prompt\n\ndiff", Hidden: true},
+		{Role: Assistant, Content: "review"},
+		{Role: User, Content: "why?"},
+	}, inner.messages)
+	assert.Equal(t, "diff", messages[1].Content)
+}
+
+func TestFeaturesClient_WithoutStreaming(t *testing.T) {
+	inner := &featuresTestClient{}
+	model := chatModel(LLMProviderOllama, "tiny", 32_768, 8_192, 0, 0)
+	model.Streaming = false
+	client := NewFeaturesClient(inner, model, HeuristicTokenizer{})
+
+	events := collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "diff"}}))
+
+	assert.Equal(t, "Send", inner.method)
+	assert.Equal(t, []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "review"},
+		{Type: LLMStreamEventTypeComplete, Content: "review"},
+	}, events)
+}
+
+func TestFeaturesClient_WithoutUsageReporting(t *testing.T) {
+	inner := &featuresTestClient{}
+	model := chatModel(LLMProviderOllama, "tiny", 32_768, 8_192, 0, 0)
+	model.UsageReporting = false
+	client := NewFeaturesClient(inner, model, HeuristicTokenizer{})
+	messages := []Message{{Role: System, Content: "prompt"}, {Role: User, Content: "the diff"}}
+	expected := LLMTokenUsage{InputTokens: 2 + 2, OutputTokens: 2}
+
+	res, err := client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res.Usage)
+
+	events := collectStreamEvents(client.Stream(t.Context(), messages))
+	assert.Equal(t, "Stream", inner.method)
+	require.Len(t, events, 3)
+	assert.Equal(t, LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: "review", Usage: expected}, events[2])
+}
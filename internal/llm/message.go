@@ -12,4 +12,12 @@ type Message struct {
 	Role    MessageRole
 	Content string
 	Hidden  bool
+	// Interrupted marks an assistant answer cut short by the user, Content
+	// only holds what was generated before.
+	Interrupted bool
+	// Usage is the token usage of the request an assistant answer comes
+	// from, when the provider reported it.
+	Usage LLMTokenUsage
+	// AnsweredBy is set like LLMSendResponse.AnsweredBy.
+	AnsweredBy string
 }
@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ModelInfo describes what a model accepts and costs. Prices are in USD per
+// million tokens, 0 for local or unknown pricing.
+type ModelInfo struct {
+	Provider        LLMProvider
+	Name            string
+	ContextWindow   int
+	MaxOutputTokens int
+	Streaming       bool
+	UsageReporting  bool
+	SystemPrompt    bool
+	InputPrice      float64
+	OutputPrice     float64
+}
+
+// DefaultDiffTokenLimit leaves room in the context window for the answer,
+// the instructions and a few chat turns.
+func (m ModelInfo) DefaultDiffTokenLimit() int {
+	return (m.ContextWindow - m.MaxOutputTokens) * 4 / 5
+}
+
+// Cost returns the USD price of a request.
+func (m ModelInfo) Cost(usage LLMTokenUsage) float64 {
+	return (float64(usage.InputTokens)*m.InputPrice + float64(usage.OutputTokens)*m.OutputPrice) / 1_000_000
+}
+
+func chatModel(provider LLMProvider, name string, contextWindow int, maxOutputTokens int, inputPrice float64, outputPrice float64) ModelInfo {
+	return ModelInfo{
+		Provider:        provider,
+		Name:            name,
+		ContextWindow:   contextWindow,
+		MaxOutputTokens: maxOutputTokens,
+		Streaming:       true,
+		UsageReporting:  true,
+		SystemPrompt:    true,
+		InputPrice:      inputPrice,
+		OutputPrice:     outputPrice,
+	}
+}
+
+var knownModels = []ModelInfo{
+	chatModel(LLMProviderOpenAI, "gpt-5", 400_000, 128_000, 1.25, 10),
+	chatModel(LLMProviderOpenAI, "gpt-5-mini", 400_000, 128_000, 0.25, 2),
+	chatModel(LLMProviderOpenAI, "gpt-5-nano", 400_000, 128_000, 0.05, 0.40),
+	chatModel(LLMProviderOpenAI, "gpt-4.1", 1_047_576, 32_768, 2, 8),
+	chatModel(LLMProviderOpenAI, "gpt-4.1-mini", 1_047_576, 32_768, 0.40, 1.60),
+	chatModel(LLMProviderOpenAI, "gpt-4.1-nano", 1_047_576, 32_768, 0.10, 0.40),
+	chatModel(LLMProviderOpenAI, "gpt-4o", 128_000, 16_384, 2.50, 10),
+	chatModel(LLMProviderOpenAI, "gpt-4o-mini", 128_000, 16_384, 0.15, 0.60),
+	chatModel(LLMProviderOpenAI, "o3", 200_000, 100_000, 2, 8),
+	chatModel(LLMProviderOpenAI, "o3-mini", 200_000, 100_000, 1.10, 4.40),
+	chatModel(LLMProviderOpenAI, "o4-mini", 200_000, 100_000, 1.10, 4.40),
+	chatModel(LLMProviderAnthropic, "claude-opus-4", 200_000, 32_000, 15, 75),
+	chatModel(LLMProviderAnthropic, "claude-sonnet-4", 200_000, 64_000, 3, 15),
+	chatModel(LLMProviderAnthropic, "claude-3-7-sonnet", 200_000, 64_000, 3, 15),
+	chatModel(LLMProviderAnthropic, "claude-3-5-haiku", 200_000, 8_192, 0.80, 4),
+	chatModel(LLMProviderGemini, "gemini-2.5-pro", 1_048_576, 65_536, 1.25, 10),
+	chatModel(LLMProviderGemini, "gemini-2.5-flash", 1_048_576, 65_536, 0.30, 2.50),
+	chatModel(LLMProviderGemini, "gemini-2.0-flash", 1_048_576, 8_192, 0.10, 0.40),
+	chatModel(LLMProviderOllama, "qwen2.5-coder", 32_768, 8_192, 0, 0),
+	chatModel(LLMProviderOllama, "llama3.1", 131_072, 8_192, 0, 0),
+	chatModel(LLMProviderOllama, "deepseek-coder-v2", 163_840, 8_192, 0, 0),
+}
+
+// ModelRegistry holds the known models per provider.
+type ModelRegistry struct {
+	models map[LLMProvider]map[string]ModelInfo
+}
+
+// NewModelRegistry returns a registry with the built-in models.
+func NewModelRegistry() *ModelRegistry {
+	registry := &ModelRegistry{models: map[LLMProvider]map[string]ModelInfo{}}
+	for _, model := range knownModels {
+		registry.Register(model)
+	}
+	return registry
+}
+
+// Register adds a model, replacing the one with the same provider and name.
+func (r *ModelRegistry) Register(model ModelInfo) {
+	if r.models[model.Provider] == nil {
+		r.models[model.Provider] = map[string]ModelInfo{}
+	}
+	r.models[model.Provider][model.Name] = model
+}
+
+// Lookup finds a model by exact name, else by the longest registered name
+// prefixing it, so dated versions (gpt-4o-2024-08-06) and ollama tags
+// (qwen2.5-coder:7b) match their family. Azure OpenAI deployments share the
+// openai models.
+func (r *ModelRegistry) Lookup(provider LLMProvider, name string) (ModelInfo, bool) {
+	if model, ok := r.lookup(provider, name); ok {
+		return model, true
+	}
+	if provider == LLMProviderAzure {
+		return r.lookup(LLMProviderOpenAI, name)
+	}
+	return ModelInfo{}, false
+}
+
+func (r *ModelRegistry) lookup(provider LLMProvider, name string) (ModelInfo, bool) {
+	models := r.models[provider]
+	if model, ok := models[name]; ok {
+		return model, true
+	}
+
+	var best ModelInfo
+	found := false
+	for prefix, model := range models {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best.Name) {
+			best, found = model, true
+		}
+	}
+	return best, found
+}
+
+type modelsFile struct {
+	Models []modelsFileEntry `json:"models"`
+}
+
+// modelsFileEntry leaves the features enabled unless set to false.
+type modelsFileEntry struct {
+	Provider        LLMProvider `json:"provider"`
+	Name            string      `json:"name"`
+	ContextWindow   int         `json:"context_window"`
+	MaxOutputTokens int         `json:"max_output_tokens"`
+	Streaming       *bool       `json:"streaming"`
+	UsageReporting  *bool       `json:"usage_reporting"`
+	SystemPrompt    *bool       `json:"system_prompt"`
+	InputPrice      float64     `json:"input_price"`
+	OutputPrice     float64     `json:"output_price"`
+}
+
+// LoadFile registers the models of a JSON file, see README for its format.
+func (r *ModelRegistry) LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file modelsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("invalid models file %s: %v", path, err)
+	}
+
+	for i, entry := range file.Models {
+		if entry.Provider == "" || entry.Name == "" {
+			return fmt.Errorf("invalid models file %s: model %d must have a provider and a name", path, i+1)
+		}
+		if entry.ContextWindow <= entry.MaxOutputTokens {
+			return fmt.Errorf("invalid models file %s: context_window of %s must be greater than max_output_tokens", path, entry.Name)
+		}
+		r.Register(ModelInfo{
+			Provider:        entry.Provider,
+			Name:            entry.Name,
+			ContextWindow:   entry.ContextWindow,
+			MaxOutputTokens: entry.MaxOutputTokens,
+			Streaming:       entry.Streaming == nil || *entry.Streaming,
+			UsageReporting:  entry.UsageReporting == nil || *entry.UsageReporting,
+			SystemPrompt:    entry.SystemPrompt == nil || *entry.SystemPrompt,
+			InputPrice:      entry.InputPrice,
+			OutputPrice:     entry.OutputPrice,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelRegistry_Lookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		model    string
+		expected string
+		found    bool
+	}{
+		{name: "exact name", provider: LLMProviderOpenAI, model: "gpt-4o", expected: "gpt-4o", found: true},
+		{name: "longest prefix", provider: LLMProviderOpenAI, model: "gpt-4o-mini-2024-07-18", expected: "gpt-4o-mini", found: true},
+		{name: "dated version", provider: LLMProviderAnthropic, model: "claude-sonnet-4-20250514", expected: "claude-sonnet-4", found: true},
+		{name: "ollama tag", provider: LLMProviderOllama, model: "qwen2.5-coder:7b", expected: "qwen2.5-coder", found: true},
+		{name: "azure uses openai models", provider: LLMProviderAzure, model: "gpt-4.1", expected: "gpt-4.1", found: true},
+		{name: "other provider model", provider: LLMProviderOllama, model: "gpt-4o"},
+		{name: "unknown model", provider: LLMProviderOpenAI, model: "davinci"},
+	}
+
+	registry := NewModelRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, found := registry.Lookup(tt.provider, tt.model)
+
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, model.Name)
+		})
+	}
+}
+
+func TestModelInfo_DefaultDiffTokenLimit(t *testing.T) {
+	model, _ := NewModelRegistry().Lookup(LLMProviderOpenAI, "gpt-4o")
+
+	assert.Equal(t, 89_292, model.DefaultDiffTokenLimit())
+}
+
+func TestModelInfo_Cost(t *testing.T) {
+	model := ModelInfo{InputPrice: 2, OutputPrice: 8}
+
+	assert.InDelta(t, 0.006, model.Cost(LLMTokenUsage{InputTokens: 1000, OutputTokens: 500}), 1e-9)
+}
+
+func TestModelRegistry_LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"models": [
+		{"provider": "ollama", "name": "private-coder", "context_window": 16384, "max_output_tokens": 4096, "streaming": false},
+		{"provider": "openai", "name": "gpt-4o", "context_window": 64000, "max_output_tokens": 4000, "input_price": 1, "output_price": 2}
+	]}`), 0o600))
+	registry := NewModelRegistry()
+
+	require.NoError(t, registry.LoadFile(path))
+
+	private, found := registry.Lookup(LLMProviderOllama, "private-coder:latest")
+	assert.True(t, found)
+	assert.Equal(t, ModelInfo{
+		Provider:        LLMProviderOllama,
+		Name:            "private-coder",
+		ContextWindow:   16384,
+		MaxOutputTokens: 4096,
+		UsageReporting:  true,
+		SystemPrompt:    true,
+	}, private)
+	overridden, _ := registry.Lookup(LLMProviderOpenAI, "gpt-4o")
+	assert.Equal(t, 64000, overridden.ContextWindow)
+	assert.Equal(t, 1.0, overridden.InputPrice)
+}
+
+func TestModelRegistry_LoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "invalid json", content: `{"models": [`, expected: "invalid models file"},
+		{name: "missing name", content: `{"models": [{"provider": "ollama", "context_window": 10}]}`, expected: "model 1 must have a provider and a name"},
+		{name: "no room for the diff", content: `{"models": [{"provider": "ollama", "name": "m", "context_window": 10, "max_output_tokens": 10}]}`, expected: "context_window of m must be greater than max_output_tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "models.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			err := NewModelRegistry().LoadFile(path)
+
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+
+	assert.Error(t, NewModelRegistry().LoadFile(filepath.Join(t.TempDir(), "missing.json")))
+}
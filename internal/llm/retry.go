@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DEFAULT_RETRY_BASE_DELAY = time.Second
+	DEFAULT_RETRY_MAX_DELAY  = 30 * time.Second
+)
+
+type RetryOptions struct {
+	// MaxAttempts counts the first request, 1 disables retries.
+	MaxAttempts int
+	// BaseDelay doubles after every attempt up to MaxDelay. A Retry-After
+	// above MaxDelay is not waited for and the error is returned.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// OnRetry is called before waiting delay for the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+type retryingClient struct {
+	client LLMClient
+	opts   RetryOptions
+	// jitter and sleep are replaced in tests.
+	jitter func(delay time.Duration) time.Duration
+	sleep  func(ctx context.Context, delay time.Duration) error
+}
+
+// NewRetryingClient retries the requests of client failing with a rate limit,
+// timeout, server or connection error. A stream is only retried if it failed
+// before sending any content.
+func NewRetryingClient(client LLMClient, opts RetryOptions) LLMClient {
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	return &retryingClient{
+		client: client,
+		opts:   opts,
+		jitter: equalJitter,
+		sleep:  sleepContext,
+	}
+}
+
+func (c *retryingClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := c.client.Send(ctx, messages)
+		if err == nil || !c.wait(ctx, attempt, err) {
+			return res, err
+		}
+	}
+}
+
+func (c *retryingClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		for attempt := 1; ; attempt++ {
+			var failure *LLMStreamEvent
+			started := false
+			for event := range c.client.Stream(ctx, messages) {
+				if event.Type == LLMStreamEventTypeError && !started {
+					failure = &event
+					continue
+				}
+				started = started || event.Type == LLMStreamEventTypeMessage
+				out <- event
+			}
+			if failure == nil {
+				return
+			}
+			if !c.wait(ctx, attempt, failure.Err) {
+				out <- *failure
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// wait sleeps before the attempt following a failed one, and reports false
+// when err should be returned instead.
+func (c *retryingClient) wait(ctx context.Context, attempt int, err error) bool {
+	if attempt >= c.opts.MaxAttempts || ctx.Err() != nil || !ClassifyError(err).Retryable() {
+		return false
+	}
+
+	delay := RetryAfter(err)
+	if delay > c.opts.MaxDelay {
+		return false
+	}
+	if delay == 0 {
+		backoff := c.opts.BaseDelay
+		for i := 1; i < attempt && backoff < c.opts.MaxDelay; i++ {
+			backoff *= 2
+		}
+		delay = c.jitter(min(backoff, c.opts.MaxDelay))
+	}
+
+	if c.opts.OnRetry != nil {
+		c.opts.OnRetry(attempt, err, delay)
+	}
+	return c.sleep(ctx, delay) == nil
+}
+
+// equalJitter waits between half and all of delay, so concurrent clients
+// don't retry in lockstep.
+func equalJitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retryTestClient answers the n-th request with sends[n] or streams[n].
+type retryTestClient struct {
+	calls   int
+	sends   []error
+	streams [][]LLMStreamEvent
+}
+
+func (c *retryTestClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	err := c.sends[c.calls]
+	c.calls++
+	if err != nil {
+		return nil, err
+	}
+	return &LLMSendResponse{Content: "review"}, nil
+}
+
+func (c *retryTestClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	events := c.streams[c.calls]
+	c.calls++
+	out := make(chan LLMStreamEvent, len(events))
+	for _, event := range events {
+		out <- event
+	}
+	close(out)
+	return out
+}
+
+// newTestRetryingClient records the delays instead of sleeping, without
+// jitter.
+func newTestRetryingClient(client LLMClient, opts RetryOptions) (*retryingClient, *[]time.Duration) {
+	var delays []time.Duration
+	retrying := NewRetryingClient(client, opts).(*retryingClient)
+	retrying.jitter = func(delay time.Duration) time.Duration { return delay }
+	retrying.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return ctx.Err()
+	}
+	return retrying, &delays
+}
+
+func TestRetryingClient_Send(t *testing.T) {
+	rateLimited := &LLMAPIError{StatusCode: 429, Message: "rate limited"}
+	serverErr := &LLMAPIError{StatusCode: 500, Message: "server error"}
+	authErr := &LLMAPIError{StatusCode: 401, Message: "unauthorized"}
+
+	tests := []struct {
+		name           string
+		sends          []error
+		expectedErr    error
+		expectedCalls  int
+		expectedDelays []time.Duration
+	}{
+		{
+			name:          "success",
+			sends:         []error{nil},
+			expectedCalls: 1,
+		},
+		{
+			name:           "exponential backoff until success",
+			sends:          []error{serverErr, serverErr, serverErr, nil},
+			expectedCalls:  4,
+			expectedDelays: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:           "max attempts",
+			sends:          []error{serverErr, serverErr, serverErr, serverErr, serverErr},
+			expectedErr:    serverErr,
+			expectedCalls:  5,
+			expectedDelays: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:           "retry after",
+			sends:          []error{&LLMAPIError{StatusCode: 429, RetryAfter: 2500 * time.Millisecond}, nil},
+			expectedCalls:  2,
+			expectedDelays: []time.Duration{2500 * time.Millisecond},
+		},
+		{
+			name:          "retry after above max delay",
+			sends:         []error{&LLMAPIError{StatusCode: 429, RetryAfter: time.Minute}},
+			expectedErr:   &LLMAPIError{StatusCode: 429, RetryAfter: time.Minute},
+			expectedCalls: 1,
+		},
+		{
+			name:          "not retryable",
+			sends:         []error{authErr},
+			expectedErr:   authErr,
+			expectedCalls: 1,
+		},
+		{
+			name:           "rate limit then not retryable",
+			sends:          []error{rateLimited, authErr},
+			expectedErr:    authErr,
+			expectedCalls:  2,
+			expectedDelays: []time.Duration{time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &retryTestClient{sends: tt.sends}
+			retrying, delays := newTestRetryingClient(client, RetryOptions{MaxAttempts: 5, MaxDelay: 3 * time.Second})
+
+			res, err := retrying.Send(t.Context(), nil)
+
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				assert.Nil(t, res)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "review", res.Content)
+			}
+			assert.Equal(t, tt.expectedCalls, client.calls)
+			assert.Equal(t, tt.expectedDelays, *delays)
+		})
+	}
+}
+
+func TestRetryingClient_SendCancelledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	serverErr := &LLMAPIError{StatusCode: 500}
+	client := &retryTestClient{sends: []error{serverErr, nil}}
+	retrying := NewRetryingClient(client, RetryOptions{MaxAttempts: 3, BaseDelay: time.Hour}).(*retryingClient)
+	retrying.jitter = func(delay time.Duration) time.Duration { return delay }
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := retrying.Send(ctx, nil)
+
+	assert.Equal(t, serverErr, err)
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestRetryingClient_OnRetry(t *testing.T) {
+	serverErr := &LLMAPIError{StatusCode: 500}
+	var retries []int
+	client := &retryTestClient{sends: []error{serverErr, nil}}
+	retrying, _ := newTestRetryingClient(client, RetryOptions{
+		MaxAttempts: 3,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			assert.Equal(t, serverErr, err)
+			assert.Equal(t, time.Second, delay)
+			retries = append(retries, attempt)
+		},
+	})
+
+	_, err := retrying.Send(t.Context(), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, retries)
+}
+
+func TestRetryingClient_Stream(t *testing.T) {
+	serverErr := &LLMAPIError{StatusCode: 503, Message: "unavailable"}
+	failed := LLMStreamEvent{Type: LLMStreamEventTypeError, Content: "unavailable", Err: serverErr}
+	content := LLMStreamEvent{Type: LLMStreamEventTypeMessage, Content: "hello"}
+	complete := LLMStreamEvent{Type: LLMStreamEventTypeComplete, Content: "hello"}
+
+	tests := []struct {
+		name           string
+		streams        [][]LLMStreamEvent
+		expectedEvents []LLMStreamEvent
+		expectedCalls  int
+	}{
+		{
+			name:           "retried before content",
+			streams:        [][]LLMStreamEvent{{failed}, {content, complete}},
+			expectedEvents: []LLMStreamEvent{content, complete},
+			expectedCalls:  2,
+		},
+		{
+			name:           "not retried after content",
+			streams:        [][]LLMStreamEvent{{content, failed}},
+			expectedEvents: []LLMStreamEvent{content, failed},
+			expectedCalls:  1,
+		},
+		{
+			name:           "attempts exhausted",
+			streams:        [][]LLMStreamEvent{{failed}, {failed}},
+			expectedEvents: []LLMStreamEvent{failed},
+			expectedCalls:  2,
+		},
+		{
+			name:           "not retryable",
+			streams:        [][]LLMStreamEvent{{{Type: LLMStreamEventTypeError, Content: "boom", Err: errors.New("boom")}}},
+			expectedEvents: []LLMStreamEvent{{Type: LLMStreamEventTypeError, Content: "boom", Err: errors.New("boom")}},
+			expectedCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &retryTestClient{streams: tt.streams}
+			retrying, _ := newTestRetryingClient(client, RetryOptions{MaxAttempts: 2})
+
+			var events []LLMStreamEvent
+			for event := range retrying.Stream(t.Context(), nil) {
+				events = append(events, event)
+			}
+
+			assert.Equal(t, tt.expectedEvents, events)
+			assert.Equal(t, tt.expectedCalls, client.calls)
+		})
+	}
+}
+
+func TestEqualJitter(t *testing.T) {
+	for range 100 {
+		delay := equalJitter(time.Second)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseReader yields the data payloads of a text/event-stream body, skipping
+// event names, keep-alive comments and blank separators.
+type sseReader struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func newSSEReader(body io.ReadCloser) *sseReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &sseReader{body: body, scanner: scanner}
+}
+
+func (r *sseReader) Next() (string, bool) {
+	for r.scanner.Scan() {
+		data, ok := strings.CutPrefix(r.scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+		return data, true
+	}
+	return "", false
+}
+
+func (r *sseReader) Err() error {
+	return r.scanner.Err()
+}
+
+func (r *sseReader) Close() error {
+	return r.body.Close()
+}
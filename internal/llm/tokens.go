@@ -1,5 +1,95 @@
 package llm
 
+import (
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	TOKENIZER_HEURISTIC = "heuristic"
+	ENCODING_CL100K     = tiktoken.MODEL_CL100K_BASE
+	ENCODING_O200K      = tiktoken.MODEL_O200K_BASE
+)
+
+// Tokenizer counts the tokens a model sees for a text.
+type Tokenizer interface {
+	CountTokens(text string) int
+	// Name is the encoding used, or TOKENIZER_HEURISTIC.
+	Name() string
+}
+
+// openAIEncodings maps OpenAI model name prefixes to their BPE encoding, the
+// first match wins so longer prefixes come first.
+var openAIEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", ENCODING_O200K},
+	{"chatgpt-4o", ENCODING_O200K},
+	{"gpt-4.1", ENCODING_O200K},
+	{"gpt-4.5", ENCODING_O200K},
+	{"gpt-5", ENCODING_O200K},
+	{"o1", ENCODING_O200K},
+	{"o3", ENCODING_O200K},
+	{"o4", ENCODING_O200K},
+	{"gpt-4", ENCODING_CL100K},
+	{"gpt-3.5-turbo", ENCODING_CL100K},
+}
+
+// The BPE ranks are embedded in the binary, tiktoken would download them on
+// first use otherwise.
+func init() {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// loadEncoding parses the embedded BPE ranks of an encoding once.
+var loadEncoding = tiktoken.GetEncoding
+
+// NewTokenizer returns a BPE tokenizer for OpenAI models with a known
+// encoding. Other models fall back to the heuristic of
+// RoughEstimateCodeTokens.
+func NewTokenizer(model string) Tokenizer {
+	for _, candidate := range openAIEncodings {
+		if !strings.HasPrefix(model, candidate.prefix) {
+			continue
+		}
+		encoding, err := loadEncoding(candidate.encoding)
+		if err != nil {
+			break
+		}
+		return &bpeTokenizer{name: candidate.encoding, encoding: encoding}
+	}
+	return HeuristicTokenizer{}
+}
+
+// HeuristicTokenizer estimates tokens from the number of characters, for
+// models without a known encoding.
+type HeuristicTokenizer struct{}
+
+func (HeuristicTokenizer) CountTokens(text string) int {
+	return RoughEstimateCodeTokens(text)
+}
+
+func (HeuristicTokenizer) Name() string {
+	return TOKENIZER_HEURISTIC
+}
+
+type bpeTokenizer struct {
+	name     string
+	encoding *tiktoken.Tiktoken
+}
+
+func (t *bpeTokenizer) CountTokens(text string) int {
+	// Special tokens like <|endoftext|> are plain text in a diff.
+	return len(t.encoding.EncodeOrdinary(text))
+}
+
+func (t *bpeTokenizer) Name() string {
+	return t.name
+}
+
 func RoughEstimateCodeTokens(text string) int {
 	avgCharsPerToken := 3.0
 	tokens := int(float64(len([]rune(text))) / avgCharsPerToken)
@@ -1,9 +1,13 @@
 package llm
 
 import (
+	"errors"
+	"net/http"
 	"testing"
 
+	"github.com/pkoukk/tiktoken-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRoughEstimateCodeTokens(t *testing.T) {
@@ -51,3 +55,112 @@ func TestRoughEstimateCodeTokens(t *testing.T) {
 		})
 	}
 }
+
+// withTestEncoding replaces the BPE ranks, embedded in real use, by every
+// single byte plus the merges building "hello".
+func withTestEncoding(t *testing.T, loadErr error) *[]string {
+	var loaded []string
+	orig := loadEncoding
+	loadEncoding = func(name string) (*tiktoken.Tiktoken, error) {
+		loaded = append(loaded, name)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		ranks := map[string]int{}
+		for i := range 256 {
+			ranks[string([]byte{byte(i)})] = i
+		}
+		for i, merge := range []string{"he", "ll", "hell", "hello"} {
+			ranks[merge] = 256 + i
+		}
+		pattern := `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+		bpe, err := tiktoken.NewCoreBPE(ranks, map[string]int{}, pattern)
+		require.NoError(t, err)
+		return tiktoken.NewTiktoken(bpe, &tiktoken.Encoding{Name: name}, map[string]any{}), nil
+	}
+	t.Cleanup(func() { loadEncoding = orig })
+	return &loaded
+}
+
+func TestNewTokenizer(t *testing.T) {
+	tests := []struct {
+		name             string
+		model            string
+		expectedName     string
+		expectedEncoding []string
+	}{
+		{
+			name:             "o200k model",
+			model:            "gpt-4o-mini",
+			expectedName:     ENCODING_O200K,
+			expectedEncoding: []string{ENCODING_O200K},
+		},
+		{
+			name:             "cl100k model",
+			model:            "gpt-4-turbo",
+			expectedName:     ENCODING_CL100K,
+			expectedEncoding: []string{ENCODING_CL100K},
+		},
+		{
+			name:             "reasoning model",
+			model:            "o3-mini",
+			expectedName:     ENCODING_O200K,
+			expectedEncoding: []string{ENCODING_O200K},
+		},
+		{
+			name:         "unknown model",
+			model:        "qwen2.5-coder",
+			expectedName: TOKENIZER_HEURISTIC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := withTestEncoding(t, nil)
+
+			tokenizer := NewTokenizer(tt.model)
+
+			assert.Equal(t, tt.expectedName, tokenizer.Name())
+			assert.Equal(t, tt.expectedEncoding, *loaded)
+		})
+	}
+}
+
+func TestNewTokenizer_LoadErrorFallsBack(t *testing.T) {
+	withTestEncoding(t, errors.New("offline"))
+
+	tokenizer := NewTokenizer("gpt-4.1")
+
+	assert.Equal(t, HeuristicTokenizer{}, tokenizer)
+	assert.Equal(t, RoughEstimateCodeTokens("hello world"), tokenizer.CountTokens("hello world"))
+}
+
+func TestBPETokenizer_CountTokens(t *testing.T) {
+	withTestEncoding(t, nil)
+	tokenizer := NewTokenizer("gpt-4.1")
+
+	assert.Equal(t, 1, tokenizer.CountTokens("hello"))
+	// " world" has no merge, one token per byte.
+	assert.Equal(t, 7, tokenizer.CountTokens("hello world"))
+	assert.Equal(t, 0, tokenizer.CountTokens(""))
+	assert.Equal(t, len("<|endoftext|>"), tokenizer.CountTokens("<|endoftext|>"))
+}
+
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("network access during the test")
+}
+
+func TestNewTokenizer_EmbeddedEncodings(t *testing.T) {
+	orig := http.DefaultTransport
+	http.DefaultTransport = offlineTransport{}
+	t.Cleanup(func() { http.DefaultTransport = orig })
+
+	for _, model := range []string{"gpt-4o", "gpt-4"} {
+		tokenizer := NewTokenizer(model)
+
+		assert.NotEqual(t, TOKENIZER_HEURISTIC, tokenizer.Name(), model)
+		assert.Equal(t, 2, tokenizer.CountTokens("hello world"), model)
+	}
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Transcript holds the requests sent to a model and their answers, written
+// by NewRecordingClient and served back by the replay provider.
+type Transcript struct {
+	Interactions []TranscriptInteraction `json:"interactions"`
+}
+
+// TranscriptInteraction is a request and its answer. Chunks are the content
+// events of a streamed answer, Error the failure of the request.
+type TranscriptInteraction struct {
+	Messages []TranscriptMessage `json:"messages"`
+	Content  string              `json:"content"`
+	Chunks   []string            `json:"chunks,omitempty"`
+	Usage    TranscriptUsage     `json:"usage"`
+	Error    string              `json:"error,omitempty"`
+}
+
+type TranscriptMessage struct {
+	Role    MessageRole `json:"role"`
+	Content string      `json:"content"`
+}
+
+type TranscriptUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+func newTranscriptMessages(messages []Message) []TranscriptMessage {
+	transcriptMessages := make([]TranscriptMessage, len(messages))
+	for i, message := range messages {
+		transcriptMessages[i] = TranscriptMessage{Role: message.Role, Content: message.Content}
+	}
+	return transcriptMessages
+}
+
+// LoadTranscript reads a transcript file, a missing file is an empty
+// transcript when allowMissing is set.
+func LoadTranscript(path string, allowMissing bool) (*Transcript, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && allowMissing {
+		return &Transcript{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(raw, &transcript); err != nil {
+		return nil, fmt.Errorf("invalid transcript %s: %v", path, err)
+	}
+	return &transcript, nil
+}
+
+// Save writes the transcript through a rename, so an interrupted run never
+// leaves a truncated file.
+func (t *Transcript) Save(path string) error {
+	raw, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type recordingClient struct {
+	client     LLMClient
+	path       string
+	mu         sync.Mutex
+	transcript *Transcript
+}
+
+// NewRecordingClient appends every request of client and its answer to the
+// transcript file at path, created when missing. The file is saved after
+// each answer. Cancelled requests are not recorded, their error tells about
+// the user rather than the model.
+func NewRecordingClient(client LLMClient, path string) (LLMClient, error) {
+	transcript, err := LoadTranscript(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingClient{client: client, path: path, transcript: transcript}, nil
+}
+
+func (c *recordingClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	res, err := c.client.Send(ctx, messages)
+	if ctx.Err() != nil {
+		return res, err
+	}
+	interaction := TranscriptInteraction{Messages: newTranscriptMessages(messages)}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.Content = res.Content
+		interaction.Usage = TranscriptUsage(res.Usage)
+	}
+	if saveErr := c.record(interaction); saveErr != nil && err == nil {
+		return nil, saveErr
+	}
+	return res, err
+}
+
+// Stream records the answer once it completed or failed, a stream closed
+// early by a cancelled request is not recorded.
+func (c *recordingClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent)
+	go func() {
+		defer close(out)
+		interaction := TranscriptInteraction{Messages: newTranscriptMessages(messages), Chunks: []string{}}
+		var content strings.Builder
+		for event := range c.client.Stream(ctx, messages) {
+			switch event.Type {
+			case LLMStreamEventTypeMessage:
+				content.WriteString(event.Content)
+				interaction.Chunks = append(interaction.Chunks, event.Content)
+			case LLMStreamEventTypeComplete:
+				interaction.Content = content.String()
+				interaction.Usage = TranscriptUsage(event.Usage)
+				if err := c.record(interaction); err != nil {
+					out <- LLMStreamEvent{Type: LLMStreamEventTypeError, Content: err.Error(), Err: err}
+					return
+				}
+			case LLMStreamEventTypeError:
+				if ctx.Err() != nil {
+					break
+				}
+				interaction.Content = content.String()
+				interaction.Error = event.Content
+				// the request already failed, its own error matters more
+				_ = c.record(interaction)
+			}
+			out <- event
+		}
+	}()
+	return out
+}
+
+func (c *recordingClient) record(interaction TranscriptInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transcript.Interactions = append(c.transcript.Interactions, interaction)
+	if err := c.transcript.Save(c.path); err != nil {
+		return fmt.Errorf("failed to record transcript: %v", err)
+	}
+	return nil
+}
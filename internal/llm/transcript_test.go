@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transcriptTestClient answers every request with res, or with the events
+// of a stream.
+type transcriptTestClient struct {
+	res    *LLMSendResponse
+	err    error
+	events []LLMStreamEvent
+}
+
+func (c *transcriptTestClient) Send(ctx context.Context, messages []Message) (*LLMSendResponse, error) {
+	return c.res, c.err
+}
+
+func (c *transcriptTestClient) Stream(ctx context.Context, messages []Message) <-chan LLMStreamEvent {
+	out := make(chan LLMStreamEvent, len(c.events))
+	for _, event := range c.events {
+		out <- event
+	}
+	close(out)
+	return out
+}
+
+func TestRecordingClient_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	messages := []Message{{Role: System, Content: "prompt", Hidden: true}, {Role: User, Content: "diff"}}
+	inner := &transcriptTestClient{res: &LLMSendResponse{Content: "review", Usage: LLMTokenUsage{InputTokens: 10, OutputTokens: 2}}}
+	client, err := NewRecordingClient(inner, path)
+	require.NoError(t, err)
+
+	res, err := client.Send(t.Context(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, inner.res, res)
+
+	inner.res, inner.err = nil, errors.New("rate limited")
+	_, err = client.Send(t.Context(), messages)
+	assert.EqualError(t, err, "rate limited")
+
+	transcript, err := LoadTranscript(path, false)
+	require.NoError(t, err)
+	request := []TranscriptMessage{{Role: System, Content: "prompt"}, {Role: User, Content: "diff"}}
+	assert.Equal(t, []TranscriptInteraction{
+		{Messages: request, Content: "review", Usage: TranscriptUsage{InputTokens: 10, OutputTokens: 2}},
+		{Messages: request, Error: "rate limited"},
+	}, transcript.Interactions)
+}
+
+func TestRecordingClient_Stream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"interactions": [{"messages": [], "content": "earlier"}]}`), 0o600))
+	events := []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "hel"},
+		{Type: LLMStreamEventTypeMessage, Content: "lo"},
+		{Type: LLMStreamEventTypeComplete, Content: "hello", Usage: LLMTokenUsage{InputTokens: 3, OutputTokens: 1}},
+	}
+	client, err := NewRecordingClient(&transcriptTestClient{events: events}, path)
+	require.NoError(t, err)
+
+	assert.Equal(t, events, collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "hi"}})))
+
+	transcript, err := LoadTranscript(path, false)
+	require.NoError(t, err)
+	require.Len(t, transcript.Interactions, 2)
+	assert.Equal(t, "earlier", transcript.Interactions[0].Content)
+	assert.Equal(t, TranscriptInteraction{
+		Messages: []TranscriptMessage{{Role: User, Content: "hi"}},
+		Content:  "hello",
+		Chunks:   []string{"hel", "lo"},
+		Usage:    TranscriptUsage{InputTokens: 3, OutputTokens: 1},
+	}, transcript.Interactions[1])
+}
+
+func TestRecordingClient_StreamError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	events := []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "partial"},
+		{Type: LLMStreamEventTypeError, Content: "connection reset", Err: errors.New("connection reset")},
+	}
+	client, err := NewRecordingClient(&transcriptTestClient{events: events}, path)
+	require.NoError(t, err)
+
+	assert.Equal(t, events, collectStreamEvents(client.Stream(t.Context(), []Message{{Role: User, Content: "hi"}})))
+
+	transcript, err := LoadTranscript(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, []TranscriptInteraction{{
+		Messages: []TranscriptMessage{{Role: User, Content: "hi"}},
+		Content:  "partial",
+		Chunks:   []string{"partial"},
+		Error:    "connection reset",
+	}}, transcript.Interactions)
+}
+
+func TestRecordingClient_Cancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	cancelled := context.Canceled
+	events := []LLMStreamEvent{
+		{Type: LLMStreamEventTypeMessage, Content: "partial"},
+		{Type: LLMStreamEventTypeError, Content: cancelled.Error(), Err: cancelled},
+	}
+	client, err := NewRecordingClient(&transcriptTestClient{events: events, err: cancelled}, path)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	assert.Equal(t, events, collectStreamEvents(client.Stream(ctx, []Message{{Role: User, Content: "hi"}})))
+	_, err = client.Send(ctx, []Message{{Role: User, Content: "hi"}})
+	assert.Equal(t, cancelled, err)
+
+	transcript, err := LoadTranscript(path, true)
+	require.NoError(t, err)
+	assert.Empty(t, transcript.Interactions)
+}
+
+func TestNewRecordingClient_InvalidTranscript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err := NewRecordingClient(&transcriptTestClient{}, path)
+
+	assert.ErrorContains(t, err, "invalid transcript "+path)
+}
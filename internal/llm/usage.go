@@ -0,0 +1,44 @@
+package llm
+
+import "fmt"
+
+// Add sums the usage of two requests.
+func (u LLMTokenUsage) Add(other LLMTokenUsage) LLMTokenUsage {
+	return LLMTokenUsage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+	}
+}
+
+// UsageReport sums the token usage and the estimated cost of requests, which
+// may come from different models. The cost is only known when every model
+// was. Cached counts the requests answered from the response cache.
+type UsageReport struct {
+	Usage    LLMTokenUsage
+	Cost     float64
+	Requests int
+	Unpriced int
+	Cached   int
+}
+
+// Add counts a request answered by model, nil when unknown.
+func (r *UsageReport) Add(usage LLMTokenUsage, model *ModelInfo) {
+	r.Usage = r.Usage.Add(usage)
+	r.Requests++
+	if model == nil {
+		r.Unpriced++
+		return
+	}
+	r.Cost += model.Cost(usage)
+}
+
+func (r UsageReport) String() string {
+	summary := fmt.Sprintf("%d input, %d output tokens", r.Usage.InputTokens, r.Usage.OutputTokens)
+	if r.Requests > 0 && r.Unpriced == 0 {
+		summary += fmt.Sprintf(", ~$%.4f", r.Cost)
+	}
+	if r.Cached > 0 {
+		summary += fmt.Sprintf(", %d from cache", r.Cached)
+	}
+	return summary
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLLMTokenUsage_Add(t *testing.T) {
+	usage := LLMTokenUsage{InputTokens: 10, OutputTokens: 2}.Add(LLMTokenUsage{InputTokens: 5, OutputTokens: 1})
+
+	assert.Equal(t, LLMTokenUsage{InputTokens: 15, OutputTokens: 3}, usage)
+}
+
+func TestUsageReport(t *testing.T) {
+	gpt := &ModelInfo{InputPrice: 2, OutputPrice: 8}
+	local := &ModelInfo{}
+
+	tests := []struct {
+		name     string
+		add      func(r *UsageReport)
+		expected string
+	}{
+		{
+			name:     "no request",
+			add:      func(r *UsageReport) {},
+			expected: "0 input, 0 output tokens",
+		},
+		{
+			name: "priced models",
+			add: func(r *UsageReport) {
+				r.Add(LLMTokenUsage{InputTokens: 1000, OutputTokens: 500}, gpt)
+				r.Add(LLMTokenUsage{InputTokens: 2000, OutputTokens: 100}, local)
+			},
+			expected: "3000 input, 600 output tokens, ~$0.0060",
+		},
+		{
+			name: "unknown model",
+			add: func(r *UsageReport) {
+				r.Add(LLMTokenUsage{InputTokens: 1000, OutputTokens: 500}, gpt)
+				r.Add(LLMTokenUsage{InputTokens: 10, OutputTokens: 5}, nil)
+			},
+			expected: "1010 input, 505 output tokens",
+		},
+		{
+			name: "cached answers",
+			add: func(r *UsageReport) {
+				r.Add(LLMTokenUsage{}, gpt)
+				r.Cached++
+			},
+			expected: "0 input, 0 output tokens, ~$0.0000, 1 from cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report UsageReport
+			tt.add(&report)
+
+			assert.Equal(t, tt.expected, report.String())
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/git"
+)
+
+// TokenEstimator returns the number of tokens of a text.
+type TokenEstimator func(text string) int
+
+// Chunk is a part of a diff small enough to be reviewed on its own.
+type Chunk struct {
+	Paths   []string
+	Content string
+	Tokens  int
+}
+
+// SplitDiff packs whole files into chunks of at most tokenLimit tokens,
+// keeping the diff order. Files above the limit are split by hunk, and hunks
+// above the limit by line, every part repeating the file header. A single
+// line above the limit still gets its own chunk.
+func SplitDiff(files []git.FileDiff, tokenLimit int, estimate TokenEstimator) []Chunk {
+	var chunks []Chunk
+	current := Chunk{}
+	var content strings.Builder
+	flush := func() {
+		if len(current.Paths) > 0 {
+			current.Content = content.String()
+			chunks = append(chunks, current)
+		}
+		current = Chunk{}
+		content.Reset()
+	}
+
+	for _, file := range files {
+		for _, piece := range splitFile(file, tokenLimit, estimate) {
+			if current.Tokens+piece.Tokens > tokenLimit {
+				flush()
+			}
+			if len(current.Paths) == 0 || current.Paths[len(current.Paths)-1] != piece.Paths[0] {
+				current.Paths = append(current.Paths, piece.Paths[0])
+			}
+			content.WriteString(piece.Content)
+			current.Tokens += piece.Tokens
+		}
+	}
+	flush()
+	return chunks
+}
+
+func splitFile(file git.FileDiff, tokenLimit int, estimate TokenEstimator) []Chunk {
+	path := file.Path()
+	whole := Chunk{Paths: []string{path}, Content: file.Raw, Tokens: estimate(file.Raw)}
+	header, hunks := splitRawHunks(file.Raw)
+	if whole.Tokens <= tokenLimit || len(hunks) == 0 || len(hunks) != len(file.Hunks) {
+		return []Chunk{whole}
+	}
+
+	// Token counts are summed piece by piece rather than estimated again on
+	// the whole text, huge files would make that quadratic.
+	headerTokens := estimate(header)
+	var pieces []Chunk
+	var group strings.Builder
+	groupTokens := headerTokens
+	flush := func() {
+		if group.Len() > 0 {
+			pieces = append(pieces, Chunk{Paths: []string{path}, Content: header + group.String(), Tokens: groupTokens})
+		}
+		group.Reset()
+		groupTokens = headerTokens
+	}
+
+	for i, hunk := range hunks {
+		hunkTokens := estimate(hunk)
+		if headerTokens+hunkTokens > tokenLimit {
+			flush()
+			pieces = append(pieces, splitHunk(path, file.Hunks[i], header, headerTokens, tokenLimit, estimate)...)
+			continue
+		}
+		if groupTokens+hunkTokens > tokenLimit {
+			flush()
+		}
+		group.WriteString(hunk)
+		groupTokens += hunkTokens
+	}
+	flush()
+	return pieces
+}
+
+// splitRawHunks separates the file header from the text of each hunk.
+func splitRawHunks(raw string) (string, []string) {
+	var header strings.Builder
+	var hunks []string
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			hunks = append(hunks, line)
+		case len(hunks) == 0:
+			header.WriteString(line)
+		default:
+			hunks[len(hunks)-1] += line
+		}
+	}
+	return header.String(), hunks
+}
+
+// splitHunk cuts a hunk into consecutive parts, each with its own hunk header
+// so line numbers stay right.
+func splitHunk(path string, hunk git.Hunk, header string, headerTokens int, tokenLimit int, estimate TokenEstimator) []Chunk {
+	var parts []Chunk
+	baseTokens := headerTokens + estimate(formatHunkHeader(hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines, hunk.Section))
+	oldStart, newStart := hunk.OldStart, hunk.NewStart
+	var lines []string
+	oldLines, newLines, tokens := 0, 0, baseTokens
+
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		parts = append(parts, Chunk{
+			Paths:   []string{path},
+			Content: header + formatHunkHeader(oldStart, oldLines, newStart, newLines, hunk.Section) + strings.Join(lines, ""),
+			Tokens:  tokens,
+		})
+		oldStart += oldLines
+		newStart += newLines
+		lines = nil
+		oldLines, newLines, tokens = 0, 0, baseTokens
+	}
+
+	for _, line := range hunk.Lines {
+		text := formatLine(line)
+		lineTokens := estimate(text)
+		if tokens+lineTokens > tokenLimit {
+			flush()
+		}
+		lines = append(lines, text)
+		tokens += lineTokens
+		if line.Type != git.LineAdded {
+			oldLines++
+		}
+		if line.Type != git.LineDeleted {
+			newLines++
+		}
+	}
+	flush()
+	return parts
+}
+
+func formatHunkHeader(oldStart, oldLines, newStart, newLines int, section string) string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldLines, newStart, newLines)
+	if section != "" {
+		header += " " + section
+	}
+	return header + "\n"
+}
+
+func formatLine(line git.Line) string {
+	prefix := " "
+	switch line.Type {
+	case git.LineAdded:
+		prefix = "+"
+	case git.LineDeleted:
+		prefix = "-"
+	}
+	text := prefix + line.Content + "\n"
+	if line.NoNewlineAtEOF {
+		text += "\\ No newline at end of file\n"
+	}
+	return text
+}
@@ -0,0 +1,120 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countLines makes token budgets easy to reason about in tests.
+func countLines(text string) int {
+	return strings.Count(text, "\n")
+}
+
+func fileDiff(path string, hunks ...string) string {
+	return "diff --git a/" + path + " b/" + path + "\n" +
+		"index 8352675..a903574 100644\n" +
+		"--- a/" + path + "\n" +
+		"+++ b/" + path + "\n" +
+		strings.Join(hunks, "")
+}
+
+func parseFiles(t *testing.T, diff string) []git.FileDiff {
+	files, err := git.ParseDiff([]byte(diff))
+	require.NoError(t, err)
+	return files
+}
+
+func TestSplitDiff(t *testing.T) {
+	smallHunk := "@@ -1 +1 @@\n-a\n+b\n"
+	firstHunk := "@@ -1,2 +1,2 @@ func first\n-a\n+b\n c\n"
+	secondHunk := "@@ -10,2 +10,2 @@ func second\n-d\n+e\n f\n"
+
+	tests := []struct {
+		name          string
+		diff          string
+		tokenLimit    int
+		expectedPaths [][]string
+		expectedParts []string
+	}{
+		{
+			name:          "everything fits in one chunk",
+			diff:          fileDiff("a.go", smallHunk) + fileDiff("b.go", smallHunk),
+			tokenLimit:    100,
+			expectedPaths: [][]string{{"a.go", "b.go"}},
+			expectedParts: []string{fileDiff("a.go", smallHunk) + fileDiff("b.go", smallHunk)},
+		},
+		{
+			name:          "files packed in order",
+			diff:          fileDiff("a.go", smallHunk) + fileDiff("b.go", smallHunk) + fileDiff("c.go", smallHunk),
+			tokenLimit:    14,
+			expectedPaths: [][]string{{"a.go", "b.go"}, {"c.go"}},
+			expectedParts: []string{
+				fileDiff("a.go", smallHunk) + fileDiff("b.go", smallHunk),
+				fileDiff("c.go", smallHunk),
+			},
+		},
+		{
+			name:          "large file split by hunk",
+			diff:          fileDiff("big.go", firstHunk, secondHunk),
+			tokenLimit:    9,
+			expectedPaths: [][]string{{"big.go"}, {"big.go"}},
+			expectedParts: []string{
+				fileDiff("big.go", firstHunk),
+				fileDiff("big.go", secondHunk),
+			},
+		},
+		{
+			name:          "large hunk split by line",
+			diff:          fileDiff("huge.go", "@@ -1,3 +1,3 @@ func huge\n-a\n+b\n c\n-d\n+e\n\\ No newline at end of file\n"),
+			tokenLimit:    7,
+			expectedPaths: [][]string{{"huge.go"}, {"huge.go"}, {"huge.go"}},
+			expectedParts: []string{
+				fileDiff("huge.go", "@@ -1,1 +1,1 @@ func huge\n-a\n+b\n"),
+				fileDiff("huge.go", "@@ -2,2 +2,1 @@ func huge\n c\n-d\n"),
+				fileDiff("huge.go", "@@ -4,0 +3,1 @@ func huge\n+e\n\\ No newline at end of file\n"),
+			},
+		},
+		{
+			name:          "file without hunks above the limit",
+			diff:          "diff --git a/bin.dat b/bin.dat\nindex 8352675..a903574 100644\nBinary files a/bin.dat and b/bin.dat differ\n",
+			tokenLimit:    1,
+			expectedPaths: [][]string{{"bin.dat"}},
+			expectedParts: []string{"diff --git a/bin.dat b/bin.dat\nindex 8352675..a903574 100644\nBinary files a/bin.dat and b/bin.dat differ\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitDiff(parseFiles(t, tt.diff), tt.tokenLimit, countLines)
+
+			var paths [][]string
+			var parts []string
+			for _, chunk := range chunks {
+				paths = append(paths, chunk.Paths)
+				parts = append(parts, chunk.Content)
+				assert.Equal(t, countLines(chunk.Content), chunk.Tokens)
+			}
+			assert.Equal(t, tt.expectedPaths, paths)
+			assert.Equal(t, tt.expectedParts, parts)
+		})
+	}
+}
+
+func TestSplitDiff_PiecesOfOneFileShareChunk(t *testing.T) {
+	firstHunk := "@@ -1,3 +1,3 @@\n-a\n-b\n-c\n+d\n+e\n+f\n"
+	secondHunk := "@@ -20 +19,0 @@\n-x\n"
+	smallHunk := "@@ -1 +0,0 @@\n-a\n"
+	diff := fileDiff("big.go", firstHunk, secondHunk) + fileDiff("small.go", smallHunk)
+
+	chunks := SplitDiff(parseFiles(t, diff), 12, countLines)
+
+	require.Len(t, chunks, 2)
+	assert.Equal(t, []string{"big.go"}, chunks[0].Paths)
+	assert.Equal(t, fileDiff("big.go", firstHunk), chunks[0].Content)
+	assert.Equal(t, []string{"big.go", "small.go"}, chunks[1].Paths)
+	assert.Equal(t, fileDiff("big.go", secondHunk)+fileDiff("small.go", smallHunk), chunks[1].Content)
+}
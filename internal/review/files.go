@@ -0,0 +1,77 @@
+package review
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/llm"
+)
+
+const FILES_INSTRUCTIONS = "The full content of some of the modified files after the change, to understand the code around the diff."
+
+// File is the content of a modified file after the change, Changed being its
+// number of added and deleted lines.
+type File struct {
+	Path    string
+	Content string
+	Changed int
+}
+
+// SelectFiles picks the files fitting in tokenLimit once added to a request,
+// trying first the ones with the most changed lines for their size, then the
+// smallest ones. Files too large are skipped for the next ones.
+func SelectFiles(files []File, tokenLimit int, estimate TokenEstimator) []File {
+	sorted := slices.Clone(files)
+	lines := func(file File) int { return max(strings.Count(file.Content, "\n"), 1) }
+	slices.SortStableFunc(sorted, func(a, b File) int {
+		// a.Changed/lines(a) > b.Changed/lines(b) puts a first
+		if c := cmp.Compare(b.Changed*lines(a), a.Changed*lines(b)); c != 0 {
+			return c
+		}
+		return cmp.Compare(len(a.Content), len(b.Content))
+	})
+
+	var selected []File
+	tokens := estimate(filesHeader)
+	for _, file := range sorted {
+		fileTokens := estimate(fileSection(file))
+		if tokens+fileTokens > tokenLimit {
+			continue
+		}
+		selected = append(selected, file)
+		tokens += fileTokens
+	}
+	return selected
+}
+
+// WithFiles adds the content of files after the diff of the last message of
+// a request. The request is returned as is without files.
+func WithFiles(messages []llm.Message, files []File) []llm.Message {
+	if len(files) == 0 {
+		return messages
+	}
+
+	var content strings.Builder
+	content.WriteString(filesHeader)
+	for _, file := range files {
+		content.WriteString(fileSection(file))
+	}
+
+	result := append([]llm.Message{}, messages...)
+	last := &result[len(result)-1]
+	last.Content += content.String()
+	return result
+}
+
+const filesHeader = "\n\n## Files\n\n" + FILES_INSTRUCTIONS
+
+// fileSection fences the content of file with more backquotes than it holds.
+func fileSection(file File) string {
+	fence := "```"
+	for strings.Contains(file.Content, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("\n\n### %s\n\n%s\n%s\n%s", file.Path, fence, strings.TrimSuffix(file.Content, "\n"), fence)
+}
@@ -0,0 +1,53 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectFiles(t *testing.T) {
+	files := []File{
+		{Path: "large.go", Content: strings.Repeat("line\n", 40), Changed: 4},
+		{Path: "small.go", Content: "a\nb\n", Changed: 1},
+		{Path: "rewritten.go", Content: "a\nb\nc\nd\n", Changed: 8},
+		{Path: "huge.go", Content: strings.Repeat("line\n", 400), Changed: 400},
+	}
+
+	selected := SelectFiles(files, 25, countLines)
+
+	var paths []string
+	for _, file := range selected {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"rewritten.go", "small.go"}, paths)
+	assert.Empty(t, SelectFiles(files, 10, countLines))
+}
+
+func TestWithFiles(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{Role: llm.User, Content: "diff", Hidden: true},
+	}
+
+	result := WithFiles(messages, []File{
+		{Path: "a.go", Content: "package a\n"},
+		{Path: "README.md", Content: "```go\ncode\n```\n"},
+	})
+
+	assert.Equal(t, "diff", messages[1].Content)
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "diff\n\n## Files\n\n" +
+				"The full content of some of the modified files after the change, to understand the code around the diff." +
+				"\n\n### a.go\n\n```\npackage a\n```" +
+				"\n\n### README.md\n\n````\n```go\ncode\n```\n````",
+			Hidden: true,
+		},
+	}, result)
+	assert.Equal(t, messages, WithFiles(messages, nil))
+}
@@ -0,0 +1,97 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+)
+
+const (
+	CHUNK_INSTRUCTIONS = "This diff is part %d of %d of a change too large to be reviewed at once. " +
+		"Review only this part, the reviews of all parts will be merged afterwards."
+	SYNTHESIS_INSTRUCTIONS = "The change was too large to be reviewed at once, it was split in %d parts reviewed separately. " +
+		"Merge the partial reviews below into a single review following the instructions: " +
+		"group related findings, remove duplicates and keep file references."
+	SYNTHESIS_MISSING_PART = "This part could not be reviewed, mention it in the review."
+	COMMITS_INSTRUCTIONS   = "The change is made of the commits described below, followed by its diff. " +
+		"Check that each commit does what its message says and flag the ones whose description doesn't match the code."
+)
+
+// ChunkMessages builds the request reviewing one chunk out of total.
+func ChunkMessages(prompt string, chunk Chunk, index int, total int) []llm.Message {
+	return []llm.Message{
+		{
+			Role:    llm.System,
+			Content: prompt,
+			Hidden:  true,
+		},
+		{
+			Role:    llm.User,
+			Content: fmt.Sprintf(CHUNK_INSTRUCTIONS, index+1, total) + "\n\n" + chunk.Content,
+			Hidden:  true,
+		},
+	}
+}
+
+// SynthesisMessages builds the request merging the review of every chunk,
+// reviews[i] being the review of chunks[i]. An empty review marks a part that
+// could not be reviewed.
+func SynthesisMessages(prompt string, chunks []Chunk, reviews []string) []llm.Message {
+	var content strings.Builder
+	fmt.Fprintf(&content, SYNTHESIS_INSTRUCTIONS, len(chunks))
+	for i, chunk := range chunks {
+		partReview := strings.TrimSpace(reviews[i])
+		if partReview == "" {
+			partReview = SYNTHESIS_MISSING_PART
+		}
+		fmt.Fprintf(&content, "\n\n## Part %d (%s)\n\n%s", i+1, strings.Join(chunk.Paths, ", "), partReview)
+	}
+
+	return []llm.Message{
+		{
+			Role:    llm.System,
+			Content: prompt,
+			Hidden:  true,
+		},
+		{
+			Role:    llm.User,
+			Content: content.String(),
+			Hidden:  true,
+		},
+	}
+}
+
+// WithCommits adds the description of commits before the diff of the last
+// message of a request, so the model can check the change against its
+// stated intent. The request is returned as is without commits.
+func WithCommits(messages []llm.Message, commits []git.Commit) []llm.Message {
+	if len(commits) == 0 {
+		return messages
+	}
+
+	result := append([]llm.Message{}, messages...)
+	last := &result[len(result)-1]
+	last.Content = COMMITS_INSTRUCTIONS + "\n\n" + commitsSection(commits) + "\n\n## Diff\n\n" + last.Content
+	return result
+}
+
+func commitsSection(commits []git.Commit) string {
+	var content strings.Builder
+	content.WriteString("## Commits")
+	for _, commit := range commits {
+		fmt.Fprintf(&content, "\n\n### %s %s\n\nAuthor: %s\nDate: %s", shortHash(commit.Hash), commit.Subject, commit.Author, commit.Date)
+		for _, trailer := range commit.Trailers {
+			fmt.Fprintf(&content, "\n%s: %s", trailer.Key, trailer.Value)
+		}
+		if commit.Body != "" {
+			fmt.Fprintf(&content, "\n\n%s", commit.Body)
+		}
+	}
+	return content.String()
+}
+
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 12)]
+}
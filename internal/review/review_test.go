@@ -0,0 +1,83 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkMessages(t *testing.T) {
+	messages := ChunkMessages("prompt", Chunk{Paths: []string{"a.go"}, Content: "diff a.go\n"}, 1, 3)
+
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "This diff is part 2 of 3 of a change too large to be reviewed at once. " +
+				"Review only this part, the reviews of all parts will be merged afterwards.\n\ndiff a.go\n",
+			Hidden: true,
+		},
+	}, messages)
+}
+
+func TestSynthesisMessages(t *testing.T) {
+	chunks := []Chunk{
+		{Paths: []string{"a.go", "b.go"}},
+		{Paths: []string{"c.go"}},
+		{Paths: []string{"d.go"}},
+	}
+
+	messages := SynthesisMessages("prompt", chunks, []string{"review a and b\n", "review c", ""})
+
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "The change was too large to be reviewed at once, it was split in 3 parts reviewed separately. " +
+				"Merge the partial reviews below into a single review following the instructions: " +
+				"group related findings, remove duplicates and keep file references." +
+				"\n\n## Part 1 (a.go, b.go)\n\nreview a and b" +
+				"\n\n## Part 2 (c.go)\n\nreview c" +
+				"\n\n## Part 3 (d.go)\n\nThis part could not be reviewed, mention it in the review.",
+			Hidden: true,
+		},
+	}, messages)
+}
+
+func TestWithCommits(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{Role: llm.User, Content: "diff", Hidden: true},
+	}
+	commits := []git.Commit{
+		{
+			Hash:     "0123456789abcdef",
+			Author:   "Jane <jane@example.com>",
+			Date:     "2025-01-02T03:04:05+00:00",
+			Subject:  "Fix parser",
+			Body:     "Handle empty input.",
+			Trailers: []git.Trailer{{Key: "Fixes", Value: "#12"}},
+		},
+		{Hash: "abc", Author: "John <john@example.com>", Date: "2025-01-03T03:04:05+00:00", Subject: "Add tests"},
+	}
+
+	result := WithCommits(messages, commits)
+
+	assert.Equal(t, "diff", messages[1].Content)
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "The change is made of the commits described below, followed by its diff. " +
+				"Check that each commit does what its message says and flag the ones whose description doesn't match the code." +
+				"\n\n## Commits" +
+				"\n\n### 0123456789ab Fix parser\n\nAuthor: Jane <jane@example.com>\nDate: 2025-01-02T03:04:05+00:00\nFixes: #12\n\nHandle empty input." +
+				"\n\n### abc Add tests\n\nAuthor: John <john@example.com>\nDate: 2025-01-03T03:04:05+00:00" +
+				"\n\n## Diff\n\ndiff",
+			Hidden: true,
+		},
+	}, result)
+	assert.Equal(t, messages, WithCommits(messages, nil))
+}
@@ -0,0 +1,63 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+)
+
+const (
+	SERIES_COMMIT_INSTRUCTIONS = "This is commit %d of %d of a patch series reviewed one commit at a time, in order. " +
+		"Review it as an atomic change: check that it does one thing and that its message describes the code."
+	SERIES_SUMMARY_INSTRUCTIONS = "The %d commits of a patch series were reviewed one at a time, in order. " +
+		"Merge their reviews below into a review of the whole series following the instructions, " +
+		"then list the commits that should be squashed, split or reordered, and why."
+	SERIES_MISSING_COMMIT = "This commit could not be reviewed, mention it in the review."
+)
+
+// CommitMessages builds the request reviewing commit index out of total of a
+// series, from its own diff and message.
+func CommitMessages(prompt string, commit git.Commit, diff string, index int, total int) []llm.Message {
+	return []llm.Message{
+		{
+			Role:    llm.System,
+			Content: prompt,
+			Hidden:  true,
+		},
+		{
+			Role:    llm.User,
+			Content: fmt.Sprintf(SERIES_COMMIT_INSTRUCTIONS, index+1, total) + "\n\n" + commitsSection([]git.Commit{commit}) + "\n\n## Diff\n\n" + diff,
+			Hidden:  true,
+		},
+	}
+}
+
+// SeriesMessages builds the request summarizing the review of every commit
+// of a series, reviews[i] being the review of commits[i]. An empty review
+// marks a commit that could not be reviewed.
+func SeriesMessages(prompt string, commits []git.Commit, reviews []string) []llm.Message {
+	var content strings.Builder
+	fmt.Fprintf(&content, SERIES_SUMMARY_INSTRUCTIONS, len(commits))
+	for i, commit := range commits {
+		commitReview := strings.TrimSpace(reviews[i])
+		if commitReview == "" {
+			commitReview = SERIES_MISSING_COMMIT
+		}
+		fmt.Fprintf(&content, "\n\n## Commit %d %s %s\n\n%s", i+1, shortHash(commit.Hash), commit.Subject, commitReview)
+	}
+
+	return []llm.Message{
+		{
+			Role:    llm.System,
+			Content: prompt,
+			Hidden:  true,
+		},
+		{
+			Role:    llm.User,
+			Content: content.String(),
+			Hidden:  true,
+		},
+	}
+}
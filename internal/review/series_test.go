@@ -0,0 +1,49 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/klemjul/diffai/internal/git"
+	"github.com/klemjul/diffai/internal/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitMessages(t *testing.T) {
+	commit := git.Commit{Hash: "0123456789abcdef", Author: "Jane <jane@example.com>", Date: "2025-01-02", Subject: "Fix parser", Body: "Handle empty input."}
+
+	messages := CommitMessages("prompt", commit, "diff a.go\n", 1, 3)
+
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "This is commit 2 of 3 of a patch series reviewed one commit at a time, in order. " +
+				"Review it as an atomic change: check that it does one thing and that its message describes the code." +
+				"\n\n## Commits\n\n### 0123456789ab Fix parser\n\nAuthor: Jane <jane@example.com>\nDate: 2025-01-02\n\nHandle empty input." +
+				"\n\n## Diff\n\ndiff a.go\n",
+			Hidden: true,
+		},
+	}, messages)
+}
+
+func TestSeriesMessages(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "aaa", Subject: "Add parser"},
+		{Hash: "bbb", Subject: "Fix parser"},
+	}
+
+	messages := SeriesMessages("prompt", commits, []string{"review a\n", ""})
+
+	assert.Equal(t, []llm.Message{
+		{Role: llm.System, Content: "prompt", Hidden: true},
+		{
+			Role: llm.User,
+			Content: "The 2 commits of a patch series were reviewed one at a time, in order. " +
+				"Merge their reviews below into a review of the whole series following the instructions, " +
+				"then list the commits that should be squashed, split or reordered, and why." +
+				"\n\n## Commit 1 aaa Add parser\n\nreview a" +
+				"\n\n## Commit 2 bbb Fix parser\n\nThis commit could not be reviewed, mention it in the review.",
+			Hidden: true,
+		},
+	}, messages)
+}
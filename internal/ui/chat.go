@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -13,22 +14,37 @@ import (
 )
 
 type ChatTUIModel struct {
-	textInput textinput.Model
-	viewport  viewport.Model
-	messages  []llm.Message
-	title     string
-	waiting   bool
+	textInput  textinput.Model
+	viewport   viewport.Model
+	messages   []llm.Message
+	rendered   []string
+	title      string
+	waiting    bool
+	streaming  bool
+	usage      llm.UsageReport
+	answeredBy string
+	pricing    func(answeredBy string) *llm.ModelInfo
 
-	getBotResponse func(messages []llm.Message) tea.Cmd
+	ctx            context.Context
+	request        context.Context
+	cancelRequest  context.CancelFunc
+	getBotResponse func(ctx context.Context, messages []llm.Message) tea.Cmd
 }
 
 const (
 	CHAT_INPUT_PLACEHOLDER = "Type a message..."
 	CHAT_INIT_LOADING      = "Loading..."
-	CHAT_WAITING_RESPONSE  = "> ⏳ Waiting for response..."
-	CHAT_TYPING_INDICATOR  = "Bot: typing..."
+	CHAT_WAITING_RESPONSE  = "> ⏳ Waiting... (esc to cancel)"
+	CHAT_TYPING_INDICATOR  = "Bot: typing... (esc to cancel)"
+	CHAT_CANCELLING        = "> ✋ Cancelling..."
+	CHAT_INTERRUPTED       = "*[interrupted]*"
+	CHAT_USAGE             = "Usage: %s"
+	CHAT_ANSWERED_BY       = " · answered by %s"
 )
 
+// renderMarkdown is replaced in tests.
+var renderMarkdown = format.FormatMarkdown
+
 var (
 	userStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
 	botStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
@@ -38,12 +54,32 @@ var (
 	inputStyle = lipgloss.NewStyle().
 			BorderStyle(lipgloss.NormalBorder()).
 			BorderTop(true)
+	statusStyle = lipgloss.NewStyle().Faint(true)
 )
 
+// ChatStreamChunk carries a piece of the assistant answer while it is
+// generated, Next waits for the following one. The final llm.Message
+// replaces the streamed content once the generation is over.
+type ChatStreamChunk struct {
+	Content string
+	Next    tea.Cmd
+}
+
 type InitialModelOptions struct {
-	Title          string
-	GetBotResponse func(messages []llm.Message) tea.Cmd
+	Title string
+	// Context is the parent of every bot request, cancelling it stops them
+	// all. Defaults to context.Background().
+	Context context.Context
+	// GetBotResponse must stop generating once ctx is cancelled and answer
+	// with the partial llm.Message marked as Interrupted.
+	GetBotResponse func(ctx context.Context, messages []llm.Message) tea.Cmd
 	Messages       []llm.Message
+	// Usage is spent before the chat starts, the status line adds the usage
+	// of every answer to it.
+	Usage llm.UsageReport
+	// Pricing returns the model of an answer given its AnsweredBy, nil
+	// when unknown.
+	Pricing func(answeredBy string) *llm.ModelInfo
 }
 
 func InitialModel(opts InitialModelOptions) ChatTUIModel {
@@ -51,31 +87,69 @@ func InitialModel(opts InitialModelOptions) ChatTUIModel {
 	ti.Placeholder = CHAT_INPUT_PLACEHOLDER
 	ti.Focus()
 
-	return ChatTUIModel{
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m := ChatTUIModel{
 		textInput:      ti,
 		viewport:       viewport.New(0, 0),
 		title:          opts.Title,
+		ctx:            ctx,
 		getBotResponse: opts.GetBotResponse,
 		messages:       opts.Messages,
 		waiting:        true,
+		usage:          opts.Usage,
+		pricing:        opts.Pricing,
 	}
+	// Init can't keep state on the model, the first request is prepared here.
+	m.request, m.cancelRequest = context.WithCancel(ctx)
+	return m
 }
 
 func (m ChatTUIModel) Init() tea.Cmd {
 	return tea.Batch(
 		textinput.Blink,
-		m.getBotResponse(m.messages),
+		m.getBotResponse(m.request, m.messages),
 		tea.EnableMouseCellMotion,
 	)
 }
 
+// requestBotResponse starts a bot request that can be cancelled on its own.
+func (m *ChatTUIModel) requestBotResponse() tea.Cmd {
+	m.request, m.cancelRequest = context.WithCancel(m.ctx)
+	m.waiting = true
+	return m.getBotResponse(m.request, m.history())
+}
+
+// history returns the messages sent to the bot. An answer interrupted before
+// its first word stays in the view but not in the history, providers reject
+// empty messages.
+func (m ChatTUIModel) history() []llm.Message {
+	history := make([]llm.Message, 0, len(m.messages))
+	for _, msg := range m.messages {
+		if msg.Role == llm.Assistant && msg.Interrupted && strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		history = append(history, msg)
+	}
+	return history
+}
+
+// cancelling reports whether the pending request was cancelled but its
+// final message has not arrived yet.
+func (m ChatTUIModel) cancelling() bool {
+	return m.waiting && m.request != nil && m.request.Err() != nil
+}
+
 func (m ChatTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		titleLines := (len(m.title) / msg.Width) + 1
-		m.viewport = viewport.New(msg.Width, msg.Height-(3+titleLines))
+		m.viewport = viewport.New(msg.Width, msg.Height-(4+titleLines))
 		m.updateViewport()
 
 	case tea.MouseMsg:
@@ -88,15 +162,48 @@ func (m ChatTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case ChatStreamChunk:
+		if !m.streaming {
+			m.streaming = true
+			m.messages = append(m.messages, llm.Message{Role: llm.Assistant})
+		}
+		m.messages[len(m.messages)-1].Content += msg.Content
+		m.updateViewport()
+		cmd = msg.Next
+
 	case llm.Message:
 		m.waiting = false
-		m.messages = append(m.messages, msg)
+		var model *llm.ModelInfo
+		if m.pricing != nil {
+			model = m.pricing(msg.AnsweredBy)
+		}
+		m.usage.Add(msg.Usage, model)
+		m.answeredBy = msg.AnsweredBy
+		if m.cancelRequest != nil {
+			m.cancelRequest()
+		}
+		if m.streaming {
+			m.streaming = false
+			m.messages[len(m.messages)-1] = msg
+			m.rendered = m.rendered[:len(m.messages)-1]
+		} else {
+			m.messages = append(m.messages, msg)
+		}
 		m.updateViewport()
 
 	case tea.KeyMsg:
 		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
+		case tea.KeyCtrlC:
+			if m.cancelRequest != nil {
+				m.cancelRequest()
+			}
 			cmd = tea.Quit
+		case tea.KeyEsc:
+			// Only the current generation stops, the answer comes back as
+			// an interrupted llm.Message.
+			if m.waiting && m.cancelRequest != nil {
+				m.cancelRequest()
+			}
 		case tea.KeyEnter:
 			if m.textInput.Value() != "" && !m.waiting {
 				userMsg := llm.Message{
@@ -104,11 +211,10 @@ func (m ChatTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					Content: m.textInput.Value(),
 				}
 				m.messages = append(m.messages, userMsg)
-				m.waiting = true
 				m.textInput.SetValue("")
 				m.updateViewport()
 
-				cmd = m.getBotResponse(m.messages)
+				cmd = m.requestBotResponse()
 			}
 		}
 	}
@@ -124,37 +230,60 @@ func (m ChatTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateViewport renders the messages not rendered yet, and the one being
+// streamed again. The others keep their rendering in m.rendered, so a long
+// answer doesn't render the whole history on every chunk.
 func (m *ChatTUIModel) updateViewport() {
-	displayedMessages := make([]string, len(m.messages))
-	for i, msg := range m.messages {
-		if msg.Hidden {
-			continue
-		}
-		switch msg.Role {
-		case llm.Assistant:
-			out, _ := format.FormatMarkdown(msg.Content)
-			displayedMessages[i] = botStyle.Render(strings.TrimSpace(out))
-		case llm.User:
-			displayedMessages[i] = userStyle.Render(fmt.Sprintf("> %s", msg.Content))
-		}
+	if m.streaming && len(m.rendered) == len(m.messages) {
+		m.rendered = m.rendered[:len(m.messages)-1]
+	}
+	for _, msg := range m.messages[len(m.rendered):] {
+		m.rendered = append(m.rendered, renderMessage(msg))
 	}
 
-	content := strings.Join(displayedMessages, "\n\n")
-	m.viewport.SetContent(content)
+	m.viewport.SetContent(strings.Join(m.rendered, "\n\n"))
 	m.viewport.GotoBottom()
 }
 
+func renderMessage(msg llm.Message) string {
+	if msg.Hidden {
+		return ""
+	}
+	switch msg.Role {
+	case llm.Assistant:
+		content := msg.Content
+		if msg.Interrupted {
+			content = strings.TrimSpace(content + "\n\n" + CHAT_INTERRUPTED)
+		}
+		out, _ := renderMarkdown(content)
+		return botStyle.Render(strings.TrimSpace(out))
+	case llm.User:
+		return userStyle.Render(fmt.Sprintf("> %s", msg.Content))
+	}
+	return ""
+}
+
 func (m ChatTUIModel) View() string {
 	input := m.textInput.View()
 
-	if m.waiting {
+	if m.cancelling() {
+		input = CHAT_CANCELLING
+	} else if m.streaming {
+		input = CHAT_TYPING_INDICATOR
+	} else if m.waiting {
 		input = CHAT_WAITING_RESPONSE
 	}
 
+	status := fmt.Sprintf(CHAT_USAGE, m.usage)
+	if m.answeredBy != "" {
+		status += fmt.Sprintf(CHAT_ANSWERED_BY, m.answeredBy)
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		titleStyle.Width(m.viewport.Width).Render(m.title),
 		m.viewport.View(),
 		inputStyle.Width(m.viewport.Width).Render(input),
+		statusStyle.Render(status),
 	)
 }
@@ -1,15 +1,17 @@
 package ui
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/klemjul/diffai/internal/llm"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func mockGetBotResponse(messages []llm.Message) tea.Cmd {
+func mockGetBotResponse(ctx context.Context, messages []llm.Message) tea.Cmd {
 	return func() tea.Msg {
 		return llm.Message{
 			Role:    llm.Assistant,
@@ -73,7 +75,7 @@ func TestModelUpdate_WindowSizeMsg(t *testing.T) {
 			screenW:   80,
 			screenH:   24,
 			expectedW: 80,
-			expectedH: 20,
+			expectedH: 19,
 			uiTitle:   "with one line title",
 		},
 		{
@@ -81,7 +83,7 @@ func TestModelUpdate_WindowSizeMsg(t *testing.T) {
 			screenW:   10,
 			screenH:   24,
 			expectedW: 10,
-			expectedH: 19,
+			expectedH: 18,
 			uiTitle:   "with one line title",
 		},
 		{
@@ -89,7 +91,7 @@ func TestModelUpdate_WindowSizeMsg(t *testing.T) {
 			screenW:   5,
 			screenH:   24,
 			expectedW: 5,
-			expectedH: 16,
+			expectedH: 15,
 			uiTitle:   "with two lines title",
 		},
 	}
@@ -183,6 +185,72 @@ func TestModelUpdate_LLMMessage(t *testing.T) {
 	assert.Nil(t, cmd, "LLM message should not return a command")
 }
 
+func TestModelUpdate_ChatStreamChunk(t *testing.T) {
+	initModel := InitialModel(InitialModelOptions{
+		GetBotResponse: mockGetBotResponse,
+		Title:          t.Name(),
+		Messages:       []llm.Message{{Role: llm.User, Content: "Hello"}},
+	})
+	next := func() tea.Msg { return nil }
+
+	updatedModel, cmd := initModel.Update(ChatStreamChunk{Content: "Hi ", Next: next})
+	updated := updatedModel.(ChatTUIModel)
+
+	assert.True(t, updated.waiting, "waiting should stay true while streaming")
+	assert.True(t, updated.streaming)
+	assert.NotNil(t, cmd, "chunk should return the command waiting for the next one")
+	assert.Equal(t, []llm.Message{
+		{Role: llm.User, Content: "Hello"},
+		{Role: llm.Assistant, Content: "Hi "},
+	}, updated.messages)
+
+	updatedModel, _ = updated.Update(ChatStreamChunk{Content: "there", Next: next})
+	updated = updatedModel.(ChatTUIModel)
+	assert.Len(t, updated.messages, 2)
+	assert.Equal(t, "Hi there", updated.messages[1].Content)
+
+	updatedModel, cmd = updated.Update(llm.Message{Role: llm.Assistant, Content: "Hi there!"})
+	updated = updatedModel.(ChatTUIModel)
+
+	assert.Nil(t, cmd)
+	assert.False(t, updated.waiting)
+	assert.False(t, updated.streaming)
+	assert.Equal(t, []llm.Message{
+		{Role: llm.User, Content: "Hello"},
+		{Role: llm.Assistant, Content: "Hi there!"},
+	}, updated.messages)
+}
+
+func TestModelUpdate_ChatStreamChunk_RendersOnlyTheStreamedMessage(t *testing.T) {
+	var rendered []string
+	defer func(original func(string) (string, error)) { renderMarkdown = original }(renderMarkdown)
+	renderMarkdown = func(text string) (string, error) {
+		rendered = append(rendered, text)
+		return text, nil
+	}
+	initModel := InitialModel(InitialModelOptions{
+		GetBotResponse: mockGetBotResponse,
+		Title:          t.Name(),
+		Messages: []llm.Message{
+			{Role: llm.User, Content: "Hello"},
+			{Role: llm.Assistant, Content: "Hi"},
+			{Role: llm.User, Content: "More"},
+		},
+	})
+	initModel.viewport.Width = 80
+	initModel.viewport.Height = 20
+	next := func() tea.Msg { return nil }
+
+	updatedModel, _ := initModel.Update(ChatStreamChunk{Content: "a", Next: next})
+	updatedModel, _ = updatedModel.Update(ChatStreamChunk{Content: "b", Next: next})
+	updatedModel, _ = updatedModel.Update(llm.Message{Role: llm.Assistant, Content: "abc"})
+	updated := updatedModel.(ChatTUIModel)
+
+	assert.Equal(t, []string{"Hi", "a", "ab", "abc"}, rendered)
+	assert.Len(t, updated.rendered, 4)
+	assert.Contains(t, updated.viewport.View(), "abc")
+}
+
 func TestModelUpdate_KeyMsg(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -202,11 +270,11 @@ func TestModelUpdate_KeyMsg(t *testing.T) {
 			expectedCmd: tea.Quit,
 		},
 		{
-			name: "esc quits",
+			name: "esc without pending request",
 			key: tea.KeyMsg{
 				Type: tea.KeyEsc,
 			},
-			expectedCmd: tea.Quit,
+			expectedCmd: nil,
 		},
 		{
 			name: "enter with input and not waiting",
@@ -215,7 +283,7 @@ func TestModelUpdate_KeyMsg(t *testing.T) {
 			},
 			initInputValue:     "Hello",
 			initWaiting:        false,
-			expectedCmd:        mockGetBotResponse([]llm.Message{}),
+			expectedCmd:        mockGetBotResponse(context.Background(), []llm.Message{}),
 			expectedWaiting:    true,
 			expectedInputValue: "",
 			expectedMessages: []llm.Message{
@@ -291,6 +359,81 @@ func TestModelUpdate_KeyMsg(t *testing.T) {
 	}
 }
 
+func TestModelUpdate_CancelRequest(t *testing.T) {
+	var requests []context.Context
+	initModel := InitialModel(InitialModelOptions{
+		Title:   t.Name(),
+		Context: t.Context(),
+		GetBotResponse: func(ctx context.Context, messages []llm.Message) tea.Cmd {
+			requests = append(requests, ctx)
+			return func() tea.Msg { return nil }
+		},
+	})
+	initModel.Init()
+	require.Len(t, requests, 1)
+
+	updatedModel, cmd := initModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	updated := updatedModel.(ChatTUIModel)
+
+	assert.Nil(t, cmd, "esc should not quit")
+	assert.ErrorIs(t, requests[0].Err(), context.Canceled)
+	assert.True(t, updated.waiting, "waiting should last until the interrupted message comes back")
+	assert.Contains(t, updated.View(), CHAT_CANCELLING)
+
+	updatedModel, _ = updated.Update(llm.Message{Role: llm.Assistant, Content: "Partial", Interrupted: true})
+	updated = updatedModel.(ChatTUIModel)
+	updated.viewport.Width = 80
+	updated.viewport.Height = 20
+	updated.updateViewport()
+
+	assert.False(t, updated.waiting)
+	assert.Contains(t, updated.viewport.View(), "Partial")
+	assert.Contains(t, updated.viewport.View(), "interrupted")
+
+	updated.textInput.SetValue("Go on")
+	updatedModel, _ = updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated = updatedModel.(ChatTUIModel)
+
+	require.Len(t, requests, 2)
+	assert.NoError(t, requests[1].Err(), "a new request should not inherit the cancellation")
+
+	_, cmd = updated.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	assert.Equal(t, tea.Quit(), cmd())
+	assert.ErrorIs(t, requests[1].Err(), context.Canceled)
+}
+
+func TestModelUpdate_CancelBeforeFirstChunk(t *testing.T) {
+	var requests [][]llm.Message
+	initModel := InitialModel(InitialModelOptions{
+		Title:    t.Name(),
+		Messages: []llm.Message{{Role: llm.User, Content: "diff", Hidden: true}},
+		GetBotResponse: func(ctx context.Context, messages []llm.Message) tea.Cmd {
+			requests = append(requests, messages)
+			return func() tea.Msg { return nil }
+		},
+	})
+	initModel.Init()
+
+	updatedModel, _ := initModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	updatedModel, _ = updatedModel.Update(llm.Message{Role: llm.Assistant, Interrupted: true})
+	updated := updatedModel.(ChatTUIModel)
+	updated.viewport.Width = 80
+	updated.viewport.Height = 20
+	updated.updateViewport()
+	assert.Contains(t, updated.viewport.View(), "interrupted")
+
+	updated.textInput.SetValue("Try again")
+	updatedModel, _ = updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated = updatedModel.(ChatTUIModel)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, []llm.Message{
+		{Role: llm.User, Content: "diff", Hidden: true},
+		{Role: llm.User, Content: "Try again"},
+	}, requests[1])
+	assert.Len(t, updated.messages, 3, "the interrupted answer should stay in the view")
+}
+
 func TestModelUpdate_TextInputFocusBlur(t *testing.T) {
 	initModel := InitialModel(InitialModelOptions{
 		GetBotResponse: mockGetBotResponse,
@@ -386,3 +529,44 @@ func TestModelView_InputShownWhenNotWaiting(t *testing.T) {
 	assert.Contains(t, view, "Hello")
 	assert.Contains(t, view, t.Name())
 }
+
+func TestModelView_Streaming(t *testing.T) {
+	model := InitialModel(InitialModelOptions{
+		Title:          t.Name(),
+		GetBotResponse: mockGetBotResponse,
+	})
+
+	model.viewport.Width = 40
+	model.viewport.Height = 10
+	model.waiting = true
+	model.streaming = true
+
+	view := model.View()
+
+	assert.Contains(t, view, CHAT_TYPING_INDICATOR)
+	assert.NotContains(t, view, CHAT_WAITING_RESPONSE)
+}
+
+func TestModelView_UsageTotal(t *testing.T) {
+	model := InitialModel(InitialModelOptions{
+		Title:          t.Name(),
+		GetBotResponse: mockGetBotResponse,
+		Usage:          llm.UsageReport{Usage: llm.LLMTokenUsage{InputTokens: 1000, OutputTokens: 100}, Cost: 0.0028, Requests: 1},
+		Pricing: func(answeredBy string) *llm.ModelInfo {
+			assert.Equal(t, "openai:gpt-4.1", answeredBy)
+			return &llm.ModelInfo{InputPrice: 2, OutputPrice: 8}
+		},
+	})
+	model.viewport.Width = 80
+	model.viewport.Height = 10
+
+	updatedModel, _ := model.Update(llm.Message{
+		Role:       llm.Assistant,
+		Content:    "answer",
+		Usage:      llm.LLMTokenUsage{InputTokens: 500, OutputTokens: 50},
+		AnsweredBy: "openai:gpt-4.1",
+	})
+	view := updatedModel.View()
+
+	assert.Contains(t, view, "Usage: 1500 input, 150 output tokens, ~$0.0042 · answered by openai:gpt-4.1")
+}